@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sivukhin/godjot/v2/djot_html"
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+const djotExtension = ".djot"
+
+// buildOptions configures conversion of the djot files tree (godjot build)
+type buildOptions struct {
+	In         string
+	Out        string
+	Workers    int
+	Force      bool
+	Parser     djot_parser.Options
+	Standalone *djot_html.StandaloneOptions
+	// Since is the modification time of the non-input dependencies (template, references): outputs older than it are rebuilt
+	Since time.Time
+}
+
+type buildJob struct {
+	Source string
+	Target string
+	Djot   bool
+}
+
+func runBuild(args []string) {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	in := flags.String("in", "", "path to the input directory with djot files")
+	out := flags.String("out", "", "path to the output directory")
+	workers := flags.Int("j", runtime.NumCPU(), "number of parallel workers")
+	force := flags.Bool("force", false, "rebuild all files even if outputs are up to date")
+	watch := flags.Bool("watch", false, "watch input directory and rebuild changed files")
+	frontMatter := flags.Bool("front-matter", false, "parse metadata block (front matter) at the start of the documents")
+	interval := flags.Duration("interval", time.Second, "polling interval for -watch")
+	standalone := registerStandaloneFlags(flags)
+	var refs stringsFlag
+	flags.Var(&refs, "refs", "path to the djot file with shared reference definitions and footnotes (can be specified multiple times)")
+	_ = flags.Parse(args)
+	if *in == "" || *out == "" {
+		log.Fatalf("both -in and -out must be specified")
+	}
+	options := buildOptions{In: *in, Out: *out, Workers: *workers, Force: *force, Parser: djot_parser.Options{FrontMatter: *frontMatter}}
+	if standalone.standalone {
+		standaloneOptions, since, err := standalone.options()
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.Standalone, options.Since = &standaloneOptions, since
+	}
+	var previous []buildJob
+	for {
+		var built, removed int
+		references, since, err := loadReferences(refs)
+		if err == nil {
+			options.Parser.References = references
+			if since.After(options.Since) {
+				options.Since = since
+			}
+			var jobs []buildJob
+			built, jobs, err = build(options)
+			if err == nil {
+				removed, err = removeDeletedOutputs(previous, jobs)
+				previous = jobs
+			}
+		}
+		if err != nil {
+			log.Printf("build failed: %v", err)
+		} else if built > 0 || !*watch {
+			log.Printf("built %v files", built)
+		}
+		if removed > 0 {
+			log.Printf("removed %v outputs of deleted files", removed)
+		}
+		if !*watch {
+			if err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+		options.Force = false
+		time.Sleep(*interval)
+	}
+}
+
+// build converts all djot files from options.In directory to html files in options.Out directory (copying other files as is)
+// and returns number of the processed files together with all jobs of the directory; files with up-to-date outputs are skipped
+func build(options buildOptions) (int, []buildJob, error) {
+	jobs, err := collectBuildJobs(options)
+	if err != nil {
+		return 0, nil, err
+	}
+	stale := make([]buildJob, 0, len(jobs))
+	for _, job := range jobs {
+		if options.Force || !isUpToDate(job, options.Since) {
+			stale = append(stale, job)
+		}
+	}
+	var (
+		wg      sync.WaitGroup
+		errsMu  sync.Mutex
+		errs    []error
+		pending = make(chan buildJob)
+	)
+	for range max(options.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range pending {
+				if err := runBuildJob(options, job); err != nil {
+					errsMu.Lock()
+					errs = append(errs, err)
+					errsMu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, job := range stale {
+		pending <- job
+	}
+	close(pending)
+	wg.Wait()
+	if len(errs) > 0 {
+		return len(stale) - len(errs), jobs, fmt.Errorf("%v files failed, first error: %w", len(errs), errs[0])
+	}
+	return len(stale), jobs, nil
+}
+
+// collectBuildJobs returns jobs for all files of the options.In directory (except options.Out directory if it's nested)
+func collectBuildJobs(options buildOptions) ([]buildJob, error) {
+	out, err := filepath.Abs(options.Out)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []buildJob
+	err = filepath.WalkDir(options.In, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == out {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relative, err := filepath.Rel(options.In, path)
+		if err != nil {
+			return err
+		}
+		job := buildJob{Source: path, Target: filepath.Join(options.Out, relative)}
+		if strings.HasSuffix(path, djotExtension) {
+			job.Target, job.Djot = strings.TrimSuffix(job.Target, djotExtension)+".html", true
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	return jobs, err
+}
+
+// removeDeletedOutputs removes targets of the previous jobs which are absent in the current jobs (source file was deleted)
+// and returns number of the removed files
+func removeDeletedOutputs(previous, current []buildJob) (int, error) {
+	targets := make(map[string]struct{}, len(current))
+	for _, job := range current {
+		targets[job.Target] = struct{}{}
+	}
+	removed := 0
+	for _, job := range previous {
+		if _, ok := targets[job.Target]; ok {
+			continue
+		}
+		if err := os.Remove(job.Target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, err
+		} else if err == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// isUpToDate returns true if job target exists and it's not older than the job source and since time
+func isUpToDate(job buildJob, since time.Time) bool {
+	source, err := os.Stat(job.Source)
+	if err != nil {
+		return false
+	}
+	target, err := os.Stat(job.Target)
+	if err != nil {
+		return false
+	}
+	return !target.ModTime().Before(source.ModTime()) && !target.ModTime().Before(since)
+}
+
+func runBuildJob(options buildOptions, job buildJob) error {
+	if err := os.MkdirAll(filepath.Dir(job.Target), 0o755); err != nil {
+		return err
+	}
+	if !job.Djot {
+		return copyFile(job.Source, job.Target)
+	}
+	input, err := os.ReadFile(job.Source)
+	if err != nil {
+		return err
+	}
+	output, err := renderDjot(options, input)
+	if err != nil {
+		return fmt.Errorf("failed to render %v: %w", job.Source, err)
+	}
+	return os.WriteFile(job.Target, output, 0o644)
+}
+
+// renderDjot converts djot file content to html exactly as godjot build does (links to djot files are rewritten to html files)
+func renderDjot(options buildOptions, input []byte) ([]byte, error) {
+	document := options.Parser.BuildDjotDocument(input)
+	context := djot_html.New()
+	context.LinkResolver = djot_parser.DjotToHtmlResolver
+	if options.Standalone == nil {
+		return []byte(context.ConvertDjotDocument(&djot_html.HtmlWriter{}, document).String()), nil
+	}
+	var output bytes.Buffer
+	if err := djot_html.RenderStandalone(&output, context, document, *options.Standalone); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// standaloneFlags holds flags of the standalone document rendering shared by the single file mode and godjot build
+type standaloneFlags struct {
+	standalone   bool
+	templatePath string
+	lang         string
+	toc          bool
+	stylesheets  stringsFlag
+}
+
+func registerStandaloneFlags(flags *flag.FlagSet) *standaloneFlags {
+	f := &standaloneFlags{}
+	flags.BoolVar(&f.standalone, "standalone", false, "output complete html document instead of fragment")
+	flags.StringVar(&f.templatePath, "template", "", "path to the html/template file for standalone document (built-in template is used if empty)")
+	flags.StringVar(&f.lang, "lang", "", "language of the standalone document")
+	flags.BoolVar(&f.toc, "toc", false, "include table of contents into standalone document")
+	flags.Var(&f.stylesheets, "css", "stylesheet link for standalone document (can be specified multiple times)")
+	return f
+}
+
+// options returns standalone rendering options and modification time of the template file (zero if built-in template is used)
+func (f *standaloneFlags) options() (djot_html.StandaloneOptions, time.Time, error) {
+	options := djot_html.StandaloneOptions{Language: f.lang, Stylesheets: f.stylesheets, Toc: f.toc}
+	if f.templatePath == "" {
+		return options, time.Time{}, nil
+	}
+	stat, err := os.Stat(f.templatePath)
+	if err != nil {
+		return options, time.Time{}, fmt.Errorf("failed to read template file %v: %w", f.templatePath, err)
+	}
+	options.Template, err = template.New(filepath.Base(f.templatePath)).ParseFiles(f.templatePath)
+	if err != nil {
+		return options, time.Time{}, fmt.Errorf("failed to parse template file %v: %w", f.templatePath, err)
+	}
+	return options, stat.ModTime(), nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.Nil(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func setModTime(t *testing.T, path string, modified time.Time) {
+	require.Nil(t, os.Chtimes(path, modified, modified))
+}
+
+func TestCollectBuildJobs(t *testing.T) {
+	in := t.TempDir()
+	writeFiles(t, in, map[string]string{
+		"index.djot":         "# Index",
+		"docs/guide.djot":    "# Guide",
+		"static/style.css":   "body {}",
+		"site/index.html":    "<p>output</p>",
+		"docs/notes.djot.md": "not djot",
+	})
+	for _, tt := range []struct {
+		name string
+		out  string
+		jobs []buildJob
+	}{
+		{
+			name: "nested output is skipped",
+			out:  filepath.Join(in, "site"),
+			jobs: []buildJob{
+				{Source: filepath.Join(in, "docs/guide.djot"), Target: filepath.Join(in, "site/docs/guide.html"), Djot: true},
+				{Source: filepath.Join(in, "docs/notes.djot.md"), Target: filepath.Join(in, "site/docs/notes.djot.md")},
+				{Source: filepath.Join(in, "index.djot"), Target: filepath.Join(in, "site/index.html"), Djot: true},
+				{Source: filepath.Join(in, "static/style.css"), Target: filepath.Join(in, "site/static/style.css")},
+			},
+		},
+		{
+			name: "external output",
+			out:  "out",
+			jobs: []buildJob{
+				{Source: filepath.Join(in, "docs/guide.djot"), Target: filepath.Join("out", "docs/guide.html"), Djot: true},
+				{Source: filepath.Join(in, "docs/notes.djot.md"), Target: filepath.Join("out", "docs/notes.djot.md")},
+				{Source: filepath.Join(in, "index.djot"), Target: filepath.Join("out", "index.html"), Djot: true},
+				{Source: filepath.Join(in, "site/index.html"), Target: filepath.Join("out", "site/index.html")},
+				{Source: filepath.Join(in, "static/style.css"), Target: filepath.Join("out", "static/style.css")},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := collectBuildJobs(buildOptions{In: in, Out: tt.out})
+			require.Nil(t, err)
+			require.Equal(t, tt.jobs, jobs)
+		})
+	}
+}
+
+func TestIsUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.djot": "a", "a.html": "a"})
+	job := buildJob{Source: filepath.Join(dir, "a.djot"), Target: filepath.Join(dir, "a.html"), Djot: true}
+	now := time.Now().Truncate(time.Second)
+	for _, tt := range []struct {
+		name           string
+		source, target time.Time
+		since          time.Time
+		upToDate       bool
+	}{
+		{name: "newer target", source: now.Add(-time.Hour), target: now, upToDate: true},
+		{name: "same time", source: now, target: now, upToDate: true},
+		{name: "older target", source: now, target: now.Add(-time.Hour), upToDate: false},
+		{name: "dependency changed", source: now.Add(-time.Hour), target: now, since: now.Add(time.Minute), upToDate: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			setModTime(t, job.Source, tt.source)
+			setModTime(t, job.Target, tt.target)
+			require.Equal(t, tt.upToDate, isUpToDate(job, tt.since))
+		})
+	}
+	require.False(t, isUpToDate(buildJob{Source: job.Source, Target: filepath.Join(dir, "missing.html")}, time.Time{}))
+	require.False(t, isUpToDate(buildJob{Source: filepath.Join(dir, "missing.djot"), Target: job.Target}, time.Time{}))
+}
+
+func TestRenderDjotLinks(t *testing.T) {
+	for _, tt := range []struct{ djot, html string }{
+		{djot: "[guide](docs/guide.djot)", html: "<p><a href=\"docs/guide.html\">guide</a></p>\n"},
+		{djot: "[section](guide.djot#install)", html: "<p><a href=\"guide.html#install\">section</a></p>\n"},
+		{djot: "[external](https://example.com/a.djot)", html: "<p><a href=\"https://example.com/a.djot\">external</a></p>\n"},
+		{djot: "![image](image.djot)", html: "<p><img alt=\"image\" src=\"image.djot\"></p>\n"},
+		{djot: "[file](notes.txt)", html: "<p><a href=\"notes.txt\">file</a></p>\n"},
+	} {
+		t.Run(tt.djot, func(t *testing.T) {
+			output, err := renderDjot(buildOptions{}, []byte(tt.djot))
+			require.Nil(t, err)
+			require.Equal(t, tt.html, string(output))
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	in, out := t.TempDir(), t.TempDir()
+	writeFiles(t, in, map[string]string{"a.djot": "[b](b.djot)", "b.djot": "_b_", "c.txt": "c"})
+	options := buildOptions{In: in, Out: out, Workers: 2}
+
+	built, jobs, err := build(options)
+	require.Nil(t, err)
+	require.Equal(t, 3, built)
+	require.Len(t, jobs, 3)
+	output, err := os.ReadFile(filepath.Join(out, "a.html"))
+	require.Nil(t, err)
+	require.Equal(t, "<p><a href=\"b.html\">b</a></p>\n", string(output))
+
+	built, _, err = build(options)
+	require.Nil(t, err)
+	require.Equal(t, 0, built)
+
+	require.Nil(t, os.Remove(filepath.Join(in, "b.djot")))
+	require.Nil(t, os.Remove(filepath.Join(in, "c.txt")))
+	built, current, err := build(options)
+	require.Nil(t, err)
+	require.Equal(t, 0, built)
+	removed, err := removeDeletedOutputs(jobs, current)
+	require.Nil(t, err)
+	require.Equal(t, 2, removed)
+	entries, err := os.ReadDir(out)
+	require.Nil(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "a.html", entries[0].Name())
+
+	removed, err = removeDeletedOutputs(jobs, current)
+	require.Nil(t, err)
+	require.Equal(t, 0, removed)
+}
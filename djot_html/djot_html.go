@@ -1,8 +1,6 @@
 package djot_html
 
 import (
-	"strings"
-
 	"maps"
 
 	. "github.com/sivukhin/godjot/v2/djot_parser"
@@ -24,18 +22,7 @@ func BlockNodeConverter(state ConversionState[*HtmlWriter], tag string, next fun
 	return state.Writer.InTag(tag, state.Node.Attributes.Entries()...)(content).WriteString("\n")
 }
 
-var htmlReplacer = strings.NewReplacer(
-	`&`, "&amp;",
-	`<`, "&lt;",
-	`>`, "&gt;",
-	`–`, `&ndash;`,
-	`—`, `&mdash;`,
-	`“`, `&ldquo;`,
-	`”`, `&rdquo;`,
-	`‘`, `&lsquo;`,
-	`’`, `&rsquo;`,
-	`…`, `&hellip;`,
-)
+var htmlReplacer = newTextReplacer(NamedEntities)
 
 func New(converters ...map[DjotNode]Conversion[*HtmlWriter]) ConversionContext[*HtmlWriter] {
 	if len(converters) == 0 {
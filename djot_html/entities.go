@@ -0,0 +1,68 @@
+package djot_html
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+// EntityMode defines how typographic characters (quotes, dashes, ellipses, etc.) are written to the HTML output
+type EntityMode int
+
+const (
+	// NamedEntities writes typographic characters as named entities (&ldquo;) - default mode
+	NamedEntities EntityMode = iota
+	// NumericEntities writes typographic characters as numeric entities (&#8220;)
+	NumericEntities
+	// Utf8Characters writes typographic characters as is
+	Utf8Characters
+)
+
+var typographicEntities = [...]struct{ Char, Name string }{
+	{Char: "–", Name: "&ndash;"},
+	{Char: "—", Name: "&mdash;"},
+	{Char: "“", Name: "&ldquo;"},
+	{Char: "”", Name: "&rdquo;"},
+	{Char: "‘", Name: "&lsquo;"},
+	{Char: "’", Name: "&rsquo;"},
+	{Char: "„", Name: "&bdquo;"},
+	{Char: "‚", Name: "&sbquo;"},
+	{Char: "«", Name: "&laquo;"},
+	{Char: "»", Name: "&raquo;"},
+	{Char: "‹", Name: "&lsaquo;"},
+	{Char: "›", Name: "&rsaquo;"},
+	{Char: "…", Name: "&hellip;"},
+	// narrow no-break space has no named entity
+	{Char: "\u202F", Name: "&#8239;"},
+}
+
+func newTextReplacer(mode EntityMode) *strings.Replacer {
+	replacements := []string{`&`, "&amp;", `<`, "&lt;", `>`, "&gt;"}
+	for _, entity := range typographicEntities {
+		switch mode {
+		case NamedEntities:
+			replacements = append(replacements, entity.Char, entity.Name)
+		case NumericEntities:
+			replacements = append(replacements, entity.Char, fmt.Sprintf("&#%d;", []rune(entity.Char)[0]))
+		}
+	}
+	return strings.NewReplacer(replacements...)
+}
+
+func textNodeConverter(replacer *strings.Replacer) Conversion[*HtmlWriter] {
+	return func(s ConversionState[*HtmlWriter], n func(c Children)) {
+		if s.Parent != nil && (s.Parent.Attributes.Get(RawInlineFormatKey) == s.Format || s.Parent.Attributes.Get(RawBlockFormatKey) == s.Format) {
+			s.Writer.WriteString(string(s.Node.Text))
+		} else {
+			s.Writer.WriteString(replacer.Replace(string(s.Node.Text)))
+		}
+	}
+}
+
+// EntityConversionRegistry overrides TextNode conversion to write typographic characters according to the mode:
+//
+//	djot_html.New(djot_html.DefaultConversionRegistry, djot_html.EntityConversionRegistry(djot_html.Utf8Characters))
+func EntityConversionRegistry(mode EntityMode) map[DjotNode]Conversion[*HtmlWriter] {
+	return map[DjotNode]Conversion[*HtmlWriter]{TextNode: textNodeConverter(newTextReplacer(mode))}
+}
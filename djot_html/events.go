@@ -0,0 +1,239 @@
+package djot_html
+
+import (
+	"fmt"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// EventRenderer renders event stream of the document (see djot_parser.Parser.ParseEvents) with the DefaultConversionRegistry rules
+// without AST construction (custom conversion rules are not supported, use ConversionContext for them):
+//
+//	renderer := djot_html.NewEventRenderer(&djot_html.HtmlWriter{})
+//	parser.ParseEvents(djot, renderer.Handle)
+//	content := renderer.Writer.String()
+type EventRenderer struct {
+	Writer *HtmlWriter
+	// LinkResolver (optional) rewrites href of LinkNode and src of ImageNode (see ConversionContext.LinkResolver)
+	LinkResolver LinkResolver
+
+	stack []eventFrame
+	// skip is the number of open nodes which are not rendered (all events inside such nodes are ignored)
+	skip int
+	// pendingTable holds the attributes of the table which open tag depends on the first child (table can have caption)
+	pendingTable *tokenizer.Attributes
+	// symbols accumulates text of the current SymbolsNode
+	symbols []byte
+}
+
+type eventFrame struct {
+	Type       DjotNode
+	Attributes tokenizer.Attributes
+	Children   int
+	Caption    bool
+}
+
+func NewEventRenderer(writer *HtmlWriter) *EventRenderer {
+	return &EventRenderer{Writer: writer}
+}
+
+const eventRendererFormat = "html"
+
+// Handle renders single event; it always returns true and can be passed to the ParseEvents directly
+func (r *EventRenderer) Handle(event Event) bool {
+	if r.skip > 0 {
+		switch event.Kind {
+		case EnterEvent:
+			r.skip++
+		case LeaveEvent:
+			r.skip--
+		}
+		return true
+	}
+	if r.pendingTable != nil {
+		r.openTable(event.Kind == EnterEvent && event.Type == TableCaptionNode)
+	}
+	switch event.Kind {
+	case EnterEvent:
+		if r.skipNode(event) {
+			r.skip = 1
+			return true
+		}
+		if len(r.stack) > 0 {
+			r.stack[len(r.stack)-1].Children++
+		}
+		if r.LinkResolver != nil && (event.Type == LinkNode || event.Type == ImageNode) {
+			event.Attributes = r.LinkResolver.ResolveNode(TreeNode[DjotNode]{Type: event.Type, Attributes: event.Attributes}).Attributes
+		}
+		r.stack = append(r.stack, eventFrame{Type: event.Type, Attributes: event.Attributes})
+		r.enter(event)
+	case LeaveEvent:
+		frame := r.stack[len(r.stack)-1]
+		r.stack = r.stack[:len(r.stack)-1]
+		r.leave(frame)
+	case TextEvent:
+		r.text(event.Text)
+	}
+	return true
+}
+
+// skipNode returns true for the nodes which are not rendered by the DefaultConversionRegistry
+func (r *EventRenderer) skipNode(event Event) bool {
+	if _, ok := DefaultConversionRegistry[event.Type]; !ok {
+		return true
+	}
+	if event.Type == RawNode && event.Attributes.Get(RawBlockFormatKey) != eventRendererFormat {
+		return true
+	}
+	// only reference link of the FootnoteReferenceNode is rendered
+	return len(r.stack) > 0 && r.stack[len(r.stack)-1].Type == FootnoteReferenceNode && r.stack[len(r.stack)-1].Children > 0
+}
+
+func (r *EventRenderer) openTable(caption bool) {
+	attributes := *r.pendingTable
+	r.pendingTable = nil
+	r.stack[len(r.stack)-1].Caption = caption
+	r.Writer.OpenTag("table", attributes.Entries()...)
+	r.Writer.WriteString("\n")
+	if caption {
+		r.Writer.OpenTag("caption")
+	}
+}
+
+func (r *EventRenderer) enter(event Event) {
+	w, entries := r.Writer, event.Attributes.Entries()
+	switch event.Type {
+	case ThematicBreakNode:
+		w.OpenTag("hr").WriteString("\n")
+	case LineBreakNode:
+		w.OpenTag("br").WriteString("\n")
+	case ImageNode:
+		w.OpenTag("img", entries...)
+	case SymbolsNode:
+		r.symbols = r.symbols[:0]
+	case TableNode:
+		r.pendingTable = &event.Attributes
+	case ListItemNode:
+		class := event.Attributes.Get(djot_tokenizer.DjotAttributeClassKey)
+		if class == CheckedTaskItemClass || class == UncheckedTaskItemClass {
+			w.OpenTag("li")
+			w.WriteString("\n")
+			w.WriteString("<input disabled=\"\" type=\"checkbox\"")
+			if class == CheckedTaskItemClass {
+				w.WriteString(" checked=\"\"")
+			}
+			w.WriteString("/>").WriteString("\n")
+		} else {
+			w.OpenTag("li", entries...).WriteString("\n")
+		}
+	case CodeNode:
+		w.OpenTag("pre").OpenTag("code", entries...)
+	case VerbatimNode:
+		if _, ok := event.Attributes.TryGet(djot_tokenizer.InlineMathKey); ok {
+			w.OpenTag("span", append([]tokenizer.AttributeEntry{{Key: "class", Value: "math inline"}}, entries...)...).WriteString("\\(")
+		} else if _, ok := event.Attributes.TryGet(djot_tokenizer.DisplayMathKey); ok {
+			w.OpenTag("span", append([]tokenizer.AttributeEntry{{Key: "class", Value: "math display"}}, entries...)...).WriteString("\\[")
+		} else if event.Attributes.Get(RawInlineFormatKey) != eventRendererFormat {
+			w.OpenTag("code", entries...)
+		}
+	case HeadingNode:
+		w.OpenTag(fmt.Sprintf("h%v", len(event.Attributes.Get(HeadingLevelKey))), entries...)
+	default:
+		if tag, ok := inlineEventTags[event.Type]; ok {
+			w.OpenTag(tag, entries...)
+		} else if tag, ok := blockEventTags[event.Type]; ok {
+			w.OpenTag(tag, entries...).WriteString("\n")
+		}
+	}
+}
+
+func (r *EventRenderer) leave(frame eventFrame) {
+	w := r.Writer
+	switch frame.Type {
+	case SymbolsNode:
+		if symbol, ok := defaultSymbolRegistry[string(r.symbols)]; ok {
+			w.WriteString(symbol)
+		} else {
+			w.WriteString(fmt.Sprintf(":%v:", string(r.symbols)))
+		}
+	case TableCaptionNode:
+		w.CloseTag("caption")
+		w.WriteString("\n")
+		w.OpenTag("tbody")
+	case TableNode:
+		if frame.Caption {
+			w.CloseTag("tbody").CloseTag("table")
+		} else {
+			w.CloseTag("table").WriteString("\n")
+		}
+	case ListItemNode:
+		w.CloseTag("li").WriteString("\n")
+	case CodeNode:
+		w.CloseTag("code").CloseTag("pre").WriteString("\n")
+	case VerbatimNode:
+		if _, ok := frame.Attributes.TryGet(djot_tokenizer.InlineMathKey); ok {
+			w.WriteString("\\)").CloseTag("span")
+		} else if _, ok := frame.Attributes.TryGet(djot_tokenizer.DisplayMathKey); ok {
+			w.WriteString("\\]").CloseTag("span")
+		} else if frame.Attributes.Get(RawInlineFormatKey) != eventRendererFormat {
+			w.CloseTag("code")
+		}
+	case HeadingNode:
+		w.CloseTag(fmt.Sprintf("h%v", len(frame.Attributes.Get(HeadingLevelKey)))).WriteString("\n")
+	case ParagraphNode, TableHeaderNode, TableCellNode, DefinitionTermNode:
+		w.CloseTag(inlineEventTags[frame.Type]).WriteString("\n")
+	default:
+		if tag, ok := inlineEventTags[frame.Type]; ok {
+			w.CloseTag(tag)
+		} else if tag, ok := blockEventTags[frame.Type]; ok {
+			w.CloseTag(tag).WriteString("\n")
+		}
+	}
+}
+
+func (r *EventRenderer) text(text []byte) {
+	if len(r.stack) > 0 && r.stack[len(r.stack)-1].Type == SymbolsNode {
+		r.symbols = append(r.symbols, text...)
+		return
+	}
+	if len(r.stack) > 0 {
+		parent := r.stack[len(r.stack)-1].Attributes
+		if parent.Get(RawInlineFormatKey) == eventRendererFormat || parent.Get(RawBlockFormatKey) == eventRendererFormat {
+			r.Writer.WriteString(string(text))
+			return
+		}
+	}
+	r.Writer.WriteString(htmlReplacer.Replace(string(text)))
+}
+
+// inlineEventTags and blockEventTags mirror InlineNodeConverter and BlockNodeConverter usages in the DefaultConversionRegistry
+var (
+	inlineEventTags = map[DjotNode]string{
+		InsertNode:         "ins",
+		DeleteNode:         "del",
+		SuperscriptNode:    "sup",
+		SubscriptNode:      "sub",
+		HighlightedNode:    "mark",
+		EmphasisNode:       "em",
+		StrongNode:         "strong",
+		ParagraphNode:      "p",
+		LinkNode:           "a",
+		SpanNode:           "span",
+		TableHeaderNode:    "th",
+		TableCellNode:      "td",
+		DefinitionTermNode: "dt",
+	}
+	blockEventTags = map[DjotNode]string{
+		DivNode:            "div",
+		TableRowNode:       "tr",
+		TaskListNode:       "ul",
+		DefinitionListNode: "dl",
+		UnorderedListNode:  "ul",
+		OrderedListNode:    "ol",
+		DefinitionItemNode: "dd",
+		SectionNode:        "section",
+		QuoteNode:          "blockquote",
+	}
+)
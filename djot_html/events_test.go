@@ -0,0 +1,53 @@
+package djot_html
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func renderEvents(parser *Parser, document []byte) string {
+	renderer := NewEventRenderer(&HtmlWriter{})
+	parser.ParseEvents(document, renderer.Handle)
+	return renderer.Writer.String()
+}
+
+func TestEventRenderer(t *testing.T) {
+	t.Run("examples", func(t *testing.T) {
+		dir, err := os.ReadDir(examplesDir)
+		require.Nil(t, err)
+		parser := NewParser(Options{})
+		for _, entry := range dir {
+			example, ok := strings.CutSuffix(entry.Name(), ".djot")
+			if !ok {
+				continue
+			}
+			djotExample, err := os.ReadFile(path.Join(examplesDir, fmt.Sprintf("%v.djot", example)))
+			require.Nil(t, err)
+			expected := New().ConvertDjotDocument(&HtmlWriter{}, parser.Parse(djotExample)).String()
+			require.Equal(t, expected, renderEvents(parser, djotExample), "example %v: %v", example, string(djotExample))
+		}
+	})
+	t.Run("document", func(t *testing.T) {
+		document := []byte("# Table\n\n" +
+			"| a | b |\n|---|--:|\n| 1 | 2 |\n^ caption\n\n" +
+			"- [x] done\n- [ ] todo\n\n" +
+			"Note[^1] with ``<b>raw</b>``{=html} and $`x` and :smile:\n\n" +
+			"[^1]: footnote *content*\n")
+		parser := NewParser(Options{AttachFootnotes: true})
+		expected := New().ConvertDjotDocument(&HtmlWriter{}, parser.Parse(document)).String()
+		require.Equal(t, expected, renderEvents(parser, document))
+	})
+	t.Run("link resolver", func(t *testing.T) {
+		renderer := NewEventRenderer(&HtmlWriter{})
+		renderer.LinkResolver = DjotToHtmlResolver
+		NewParser(Options{}).ParseEvents([]byte("[page](page.djot)"), renderer.Handle)
+		require.Equal(t, "<p><a href=\"page.html\">page</a></p>\n", renderer.Writer.String())
+	})
+}
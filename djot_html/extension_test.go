@@ -0,0 +1,195 @@
+package djot_html
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+var (
+	mentionToken  = djot_tokenizer.NewDjotToken("MentionInline")
+	wikiLinkToken = djot_tokenizer.NewDjotToken("WikiLinkInline")
+)
+
+var extensionOptions = Options{InlineExtensions: []InlineExtension{
+	{
+		InlineExtension: djot_tokenizer.InlineExtension{
+			Type:         mentionToken,
+			StartSymbols: []byte("@"),
+			MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+				if next, ok := r.Token1(s, [...]byte{'@'}); ok {
+					return r.MaskRepeat(next, djot_tokenizer.AttributeTokenMask, 1)
+				}
+				return 0, false
+			},
+		},
+		Node: LinkNode,
+		Attributes: func(document []byte, open, _ tokenizer.Token[djot_tokenizer.DjotToken]) tokenizer.Attributes {
+			return tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: LinkHrefKey, Value: "/users/" + string(document[open.Start+1:open.End])})
+		},
+	},
+	{
+		InlineExtension: djot_tokenizer.InlineExtension{
+			Type:         wikiLinkToken,
+			StartSymbols: []byte("[]"),
+			MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+				return r.Token2(s, [...]byte{'[', '['})
+			},
+			MatchClose: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+				return r.Token2(s, [...]byte{']', ']'})
+			},
+			Verbatim: true,
+		},
+		Node: LinkNode,
+		Attributes: func(document []byte, open, close tokenizer.Token[djot_tokenizer.DjotToken]) tokenizer.Attributes {
+			return tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: LinkHrefKey, Value: "/wiki/" + string(document[open.End:close.Start])})
+		},
+	},
+}}
+
+func TestInlineExtension(t *testing.T) {
+	for _, tt := range []struct{ djot, html string }{
+		{djot: "ping @bob", html: "<p>ping <a href=\"/users/bob\">@bob</a></p>\n"},
+		{djot: "ping @bob{.user}", html: "<p>ping <a class=\"user\" href=\"/users/bob\">@bob</a></p>\n"},
+		{djot: "see [[Main_Page]] and [span](url)", html: "<p>see <a href=\"/wiki/Main_Page\">Main_Page</a> and <a href=\"url\">span</a></p>\n"},
+		{djot: "not closed [[*page*", html: "<p>not closed [[*page*</p>\n"},
+		{djot: "`@bob`", html: "<p><code>@bob</code></p>\n"},
+		{djot: "\\@bob", html: "<p>@bob</p>\n"},
+	} {
+		t.Run(tt.djot, func(t *testing.T) {
+			ast := extensionOptions.BuildDjotAst([]byte(tt.djot))
+			require.Equal(t, tt.html, New().ConvertDjot(&HtmlWriter{}, ast...).String())
+		})
+	}
+	t.Run("no extensions", func(t *testing.T) {
+		require.Equal(t, "<p>ping @bob</p>\n", printDjot("ping @bob"))
+	})
+}
+
+var (
+	admonitionToken = djot_tokenizer.NewDjotToken("AdmonitionBlock")
+	commentToken    = djot_tokenizer.NewDjotToken("CommentBlock")
+	rawLinesToken   = djot_tokenizer.NewDjotToken("RawLinesBlock")
+)
+
+func matchFence(r tokenizer.TextReader, s tokenizer.ReaderState, fence string) (tokenizer.ReaderState, bool) {
+	next, ok := r.Token(s, fence)
+	if !ok {
+		return 0, false
+	}
+	return r.EmptyOrWhiteSpace(next)
+}
+
+var blockExtensionOptions = Options{BlockExtensions: []BlockExtension{
+	{
+		BlockExtension: djot_tokenizer.BlockExtension{
+			Type: admonitionToken,
+			MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[djot_tokenizer.DjotToken], tokenizer.ReaderState, bool) {
+				next, ok := r.Token(s, "!!! ")
+				if !ok {
+					return tokenizer.Token[djot_tokenizer.DjotToken]{}, 0, false
+				}
+				kindEnd, ok := r.MaskRepeat(next, djot_tokenizer.LowerAlphaByteMask, 1)
+				if !ok {
+					return tokenizer.Token[djot_tokenizer.DjotToken]{}, 0, false
+				}
+				end, ok := r.EmptyOrWhiteSpace(kindEnd)
+				if !ok {
+					return tokenizer.Token[djot_tokenizer.DjotToken]{}, 0, false
+				}
+				return tokenizer.Token[djot_tokenizer.DjotToken]{
+					Start:      s,
+					End:        end,
+					Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: djot_tokenizer.DjotAttributeClassKey, Value: "admonition " + r.Select(next, kindEnd)}),
+				}, end, true
+			},
+			MatchClose: func(r tokenizer.TextReader, s tokenizer.ReaderState, _ tokenizer.Token[djot_tokenizer.DjotToken]) (tokenizer.ReaderState, bool) {
+				return matchFence(r, s, "!!!")
+			},
+			Container: true,
+		},
+		Node: DivNode,
+	},
+	{
+		BlockExtension: djot_tokenizer.BlockExtension{
+			Type: commentToken,
+			MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[djot_tokenizer.DjotToken], tokenizer.ReaderState, bool) {
+				next, ok := matchFence(r, s, "%%%")
+				return tokenizer.Token[djot_tokenizer.DjotToken]{
+					Start:      s,
+					End:        next,
+					Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: RawBlockFormatKey, Value: "comment"}),
+				}, next, ok
+			},
+			MatchClose: func(r tokenizer.TextReader, s tokenizer.ReaderState, _ tokenizer.Token[djot_tokenizer.DjotToken]) (tokenizer.ReaderState, bool) {
+				return matchFence(r, s, "%%%")
+			},
+		},
+		Node: RawNode,
+	},
+	{
+		BlockExtension: djot_tokenizer.BlockExtension{
+			Type: rawLinesToken,
+			MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[djot_tokenizer.DjotToken], tokenizer.ReaderState, bool) {
+				next, ok := r.Token(s, "| ")
+				return tokenizer.Token[djot_tokenizer.DjotToken]{
+					Start:      s,
+					End:        next,
+					Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: RawBlockFormatKey, Value: "html"}),
+				}, next, ok
+			},
+			MatchContinue: func(r tokenizer.TextReader, s tokenizer.ReaderState, _ tokenizer.Token[djot_tokenizer.DjotToken]) (tokenizer.ReaderState, bool) {
+				return r.Token(s, "| ")
+			},
+		},
+		Node: RawNode,
+	},
+}}
+
+func TestBlockExtension(t *testing.T) {
+	for _, tt := range []struct{ djot, html string }{
+		{
+			djot: "!!! warning\nBe *careful*\n\n- one\n- two\n!!!\nafter",
+			html: "<div class=\"admonition warning\">\n<p>Be <strong>careful</strong></p>\n<ul>\n<li>\none\n</li>\n<li>\ntwo\n</li>\n</ul>\n</div>\n<p>after</p>\n",
+		},
+		{
+			djot: "{#id}\n!!! note\n!!! tip\nnested\n!!!\n!!!\n",
+			html: "<div class=\"admonition note\" id=\"id\">\n<div class=\"admonition tip\">\n<p>nested</p>\n</div>\n</div>\n",
+		},
+		{
+			djot: "before\n\n%%%\n# not a heading\n\n*not strong*\n%%%\nafter",
+			html: "<p>before</p>\n<p>after</p>\n",
+		},
+		{
+			djot: "- item\n\n  %%%\n  hidden\n  %%%\n- next",
+			html: "<ul>\n<li>\n<p>item</p>\n</li>\n<li>\n<p>next</p>\n</li>\n</ul>\n",
+		},
+		{
+			djot: "| <b>one</b>\n| *two*\nafter\n\n> | <i>quoted</i>\n> text",
+			html: "<b>one</b>\n*two*\n<p>after</p>\n<blockquote>\n<i>quoted</i>\n<p>text</p>\n</blockquote>\n",
+		},
+		{
+			djot: "!!! unclosed\ntext",
+			html: "<div class=\"admonition unclosed\">\n<p>text</p>\n</div>\n",
+		},
+	} {
+		t.Run(tt.djot, func(t *testing.T) {
+			ast := blockExtensionOptions.BuildDjotAst([]byte(tt.djot))
+			require.Equal(t, tt.html, New().ConvertDjot(&HtmlWriter{}, ast...).String())
+		})
+	}
+	t.Run("verbatim content", func(t *testing.T) {
+		ast := blockExtensionOptions.BuildDjotAst([]byte("%%%\n# not a heading\n%%%\n"))
+		require.Equal(t, RawNode, ast[0].Children[0].Type)
+		require.Equal(t, "# not a heading\n", string(ast[0].Children[0].FullText()))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		invalid := Options{BlockExtensions: []BlockExtension{{BlockExtension: djot_tokenizer.BlockExtension{Type: admonitionToken}, Node: DivNode}}}
+		require.EqualError(t, invalid.Validate(), "block extension AdmonitionBlock: MatchOpen must be set")
+		require.Panics(t, func() { invalid.BuildDjotAst([]byte("text")) })
+	})
+}
@@ -0,0 +1,65 @@
+package djot_html
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestFootnotes(t *testing.T) {
+	djot := []byte(`First[^b], second[^a] and again[^b].
+
+[^a]: Note A
+
+[^unused]: Unused note
+
+[^b]: Note B
+`)
+	t.Run("numbering", func(t *testing.T) {
+		document := Options{}.BuildDjotDocument(djot)
+		require.Equal(t, `<p>First<a id="fnref1" href="#fn1" role="doc-noteref"><sup>1</sup></a>, second<a id="fnref2" href="#fn2" role="doc-noteref"><sup>2</sup></a> and again<a id="fnref1-2" href="#fn1" role="doc-noteref"><sup>1</sup></a>.</p>
+<section role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn1">
+<p>Note B<a href="#fnref1" role="doc-backlink">↩︎︎</a><a href="#fnref1-2" role="doc-backlink">↩︎︎<sup>2</sup></a></p>
+</li>
+<li id="fn2">
+<p>Note A<a href="#fnref2" role="doc-backlink">↩︎︎</a></p>
+</li>
+</ol>
+</section>
+`, New().ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+	t.Run("prefix", func(t *testing.T) {
+		document := Options{IdPrefix: "post-"}.BuildDjotDocument([]byte("Text[^1]\n\n[^1]: Note"))
+		require.Equal(t, `<p>Text<a id="post-fnref1" href="#post-fn1" role="doc-noteref"><sup>1</sup></a></p>
+<section role="doc-endnotes">
+<hr>
+<ol>
+<li id="post-fn1">
+<p>Note<a href="#post-fnref1" role="doc-backlink">↩︎︎</a></p>
+</li>
+</ol>
+</section>
+`, New().ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+	t.Run("not indented line closes div", func(t *testing.T) {
+		document := Options{}.BuildDjotDocument([]byte("Text[^1]\n\n[^1]: ::: note\nafter\n"))
+		require.Equal(t, `<p>Text<a id="fnref1" href="#fn1" role="doc-noteref"><sup>1</sup></a></p>
+<p>after</p>
+<section role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn1">
+<div class="note">
+</div>
+<p><a href="#fnref1" role="doc-backlink">↩︎︎</a></p>
+</li>
+</ol>
+</section>
+`, New().ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+}
@@ -0,0 +1,61 @@
+package djot_html
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestHeadingIds(t *testing.T) {
+	djot := []byte("# Examples\n\n## Examples\n\nSee [Examples][] and [second][Examples-1][^1]\n\n[^1]: note")
+	t.Run("default", func(t *testing.T) {
+		document := Options{}.BuildDjotDocument(djot)
+		require.Equal(t, `<section id="Examples">
+<h1>Examples</h1>
+</section>
+<section id="Examples-1">
+<h2>Examples</h2>
+<p>See <a href="#Examples">Examples</a> and <a href="#Examples-1">second</a><a id="fnref1" href="#fn1" role="doc-noteref"><sup>1</sup></a></p>
+</section>
+<section role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn1">
+<p>note<a href="#fnref1" role="doc-backlink">↩︎︎</a></p>
+</li>
+</ol>
+</section>
+`, New().ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+	t.Run("github slugger with prefix", func(t *testing.T) {
+		document := Options{Slugger: GithubSlugger, IdPrefix: "doc-"}.BuildDjotDocument(bytes.ReplaceAll(djot, []byte("[Examples-1]"), []byte("[examples-1]")))
+		require.Equal(t, `<section id="doc-examples">
+<h1>Examples</h1>
+</section>
+<section id="doc-examples-1">
+<h2>Examples</h2>
+<p>See <a href="#doc-examples">Examples</a> and <a href="#doc-examples-1">second</a><a id="doc-fnref1" href="#doc-fn1" role="doc-noteref"><sup>1</sup></a></p>
+</section>
+<section role="doc-endnotes">
+<hr>
+<ol>
+<li id="doc-fn1">
+<p>note<a href="#doc-fnref1" role="doc-backlink">↩︎︎</a></p>
+</li>
+</ol>
+</section>
+`, New().ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+	t.Run("explicit ids", func(t *testing.T) {
+		document := Options{}.BuildDjotDocument([]byte("# Intro\n\nSee [Intro][]\n\n{#Intro}\nexplicit"))
+		require.Equal(t, `<section id="Intro-1">
+<h1>Intro</h1>
+<p>See <a href="#Intro-1">Intro</a></p>
+<p id="Intro">explicit</p>
+</section>
+`, New().ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+}
@@ -16,13 +16,7 @@ var defaultSymbolRegistry = map[string]string{}
 var DefaultConversionRegistry = map[DjotNode]Conversion[*HtmlWriter]{
 	ThematicBreakNode: func(s ConversionState[*HtmlWriter], n func(c Children)) { s.Writer.OpenTag("hr").WriteString("\n") },
 	LineBreakNode:     func(s ConversionState[*HtmlWriter], n func(c Children)) { s.Writer.OpenTag("br").WriteString("\n") },
-	TextNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
-		if s.Parent != nil && (s.Parent.Attributes.Get(RawInlineFormatKey) == s.Format || s.Parent.Attributes.Get(RawBlockFormatKey) == s.Format) {
-			s.Writer.WriteString(string(s.Node.Text))
-		} else {
-			s.Writer.WriteString(htmlReplacer.Replace(string(s.Node.Text)))
-		}
-	},
+	TextNode:          textNodeConverter(htmlReplacer),
 	SymbolsNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
 		symbol, ok := defaultSymbolRegistry[string(s.Node.FullText())]
 		if ok {
@@ -74,7 +68,11 @@ var DefaultConversionRegistry = map[DjotNode]Conversion[*HtmlWriter]{
 	ListItemNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
 		class := s.Node.Attributes.Get(djot_tokenizer.DjotAttributeClassKey)
 		if class == CheckedTaskItemClass || class == UncheckedTaskItemClass {
-			s.Writer.InTag("li")(func() {
+			var attributes []tokenizer.AttributeEntry
+			if sourcePos, ok := s.Node.Attributes.TryGet(SourcePosKey); ok {
+				attributes = append(attributes, tokenizer.AttributeEntry{Key: SourcePosKey, Value: sourcePos})
+			}
+			s.Writer.InTag("li", attributes...)(func() {
 				s.Writer.WriteString("\n")
 				s.Writer.WriteString("<input disabled=\"\" type=\"checkbox\"")
 				if class == CheckedTaskItemClass {
@@ -96,6 +94,8 @@ var DefaultConversionRegistry = map[DjotNode]Conversion[*HtmlWriter]{
 	QuoteNode:          func(s ConversionState[*HtmlWriter], n func(c Children)) { BlockNodeConverter(s, "blockquote", n) },
 	DocumentNode:       func(s ConversionState[*HtmlWriter], n func(c Children)) { n(nil) },
 	FootnoteDefNode:    func(s ConversionState[*HtmlWriter], n func(c Children)) { n(nil) },
+	// attached footnote content is rendered in the endnotes section, so only reference link is rendered in place
+	FootnoteReferenceNode: func(s ConversionState[*HtmlWriter], n func(c Children)) { n(s.Node.Children[:1]) },
 	CodeNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
 		s.Writer.OpenTag("pre").OpenTag("code", s.Node.Attributes.Entries()...)
 		n(nil)
@@ -0,0 +1,35 @@
+package djot_html
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestLinkResolver(t *testing.T) {
+	context := New()
+	context.LinkResolver = ChainLinkResolvers(
+		DjotToHtmlResolver,
+		BaseUrlResolver("https://cdn.example.com/assets/", ImageNode),
+		BaseUrlResolver("/docs/", LinkNode),
+		PercentEncodeResolver,
+		ExternalLinkResolver("_blank"),
+	)
+	for _, tt := range []struct{ djot, html string }{
+		{djot: "[guide](guide.djot#intro)", html: `<p><a href="/docs/guide.html#intro">guide</a></p>` + "\n"},
+		{djot: "[top](#top)", html: `<p><a href="#top">top</a></p>` + "\n"},
+		{djot: "![logo](img/logo file.png)", html: `<p><img alt="logo" src="https://cdn.example.com/assets/img/logo%20file.png"></p>` + "\n"},
+		{djot: "![logo](https://example.com/logo.png)", html: `<p><img alt="logo" src="https://example.com/logo.png"></p>` + "\n"},
+		{djot: "[wiki](https://ru.wikipedia.org/wiki/Ёж)", html: `<p><a href="https://ru.wikipedia.org/wiki/%D0%81%D0%B6" rel="noopener noreferrer" target="_blank">wiki</a></p>` + "\n"},
+		{djot: "[no destination][missing]", html: `<p><a>no destination</a></p>` + "\n"},
+	} {
+		t.Run(tt.djot, func(t *testing.T) {
+			ast := BuildDjotAst([]byte(tt.djot))
+			require.Equal(t, tt.html, context.ConvertDjot(&HtmlWriter{}, ast...).String())
+			// AST must not be modified by the resolver
+			require.Equal(t, tt.html, context.ConvertDjot(&HtmlWriter{}, ast...).String())
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package djot_html
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+func TestSharedReferences(t *testing.T) {
+	references := ParseReferences([]byte(`[rfc]: https://www.rfc-editor.org/rfc/rfc9110
+[local]: https://shared.example.com
+
+{title="Glossary"}
+[glossary]: /glossary.html
+
+[^api]: Application programming interface, see [rfc][].
+`))
+	references.AddLink("go", "https://go.dev", tokenizer.AttributeEntry{Key: "class", Value: "external"})
+	options := Options{References: references}
+	t.Run("links", func(t *testing.T) {
+		document := options.BuildDjotDocument([]byte("[HTTP][rfc], [Go][go], [terms][glossary], [local][]\n\n[local]: https://local.example.com"))
+		require.Equal(t,
+			`<p><a href="https://www.rfc-editor.org/rfc/rfc9110">HTTP</a>, <a class="external" href="https://go.dev">Go</a>, `+
+				`<a href="/glossary.html" title="Glossary">terms</a>, <a href="https://local.example.com">local</a></p>`+"\n",
+			New().ConvertDjotDocument(&HtmlWriter{}, document).String(),
+		)
+	})
+	t.Run("footnotes", func(t *testing.T) {
+		document := options.BuildDjotDocument([]byte("Local[^local] and shared[^api] notes, missing[^missing]\n\n[^local]: Local note"))
+		require.Equal(t, `<p>Local<a id="fnref1" href="#fn1" role="doc-noteref"><sup>1</sup></a> and shared<a id="fnref2" href="#fn2" role="doc-noteref"><sup>2</sup></a> notes, missing<a id="fnref3" href="#fn3" role="doc-noteref"><sup>3</sup></a></p>
+<section role="doc-endnotes">
+<hr>
+<ol>
+<li id="fn1">
+<p>Local note<a href="#fnref1" role="doc-backlink">↩︎︎</a></p>
+</li>
+<li id="fn2">
+<p>Application programming interface, see <a href="https://www.rfc-editor.org/rfc/rfc9110">rfc</a>.<a href="#fnref2" role="doc-backlink">↩︎︎</a></p>
+</li>
+<li id="fn3">
+<p><a href="#fnref3" role="doc-backlink">↩︎︎</a></p>
+</li>
+</ol>
+</section>
+`, New().ConvertDjotDocument(&HtmlWriter{}, document).String())
+		// shared footnote content must stay untouched
+		require.Len(t, references.Footnotes["api"][0].Children, 3)
+	})
+}
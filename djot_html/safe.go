@@ -0,0 +1,95 @@
+package djot_html
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+var (
+	safeUrlAttributes  = map[string]struct{}{LinkHrefKey: {}, ImgSrcKey: {}, "action": {}, "formaction": {}, "poster": {}, "cite": {}, "background": {}}
+	unsafeAttributes   = map[string]struct{}{"srcdoc": {}, "srcset": {}}
+	safeStyleAttribute = regexp.MustCompile(`^text-align: (left|right|center);$`)
+	safeDataUrl        = regexp.MustCompile(`^data:image/(png|gif|jpeg|webp);`)
+)
+
+// IsSafeUrl returns false for URLs with schemes which can execute code in the browser (javascript:, vbscript:, non-image data:)
+func IsSafeUrl(url string) bool {
+	normalized := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.ToLower(html.UnescapeString(url)))
+	if strings.HasPrefix(normalized, "javascript:") || strings.HasPrefix(normalized, "vbscript:") {
+		return false
+	}
+	if strings.HasPrefix(normalized, "data:") {
+		return safeDataUrl.MatchString(normalized)
+	}
+	return true
+}
+
+// SanitizeAttributes drops event handlers, unsafe URLs and styles from the attributes and escapes all values
+func SanitizeAttributes(attributes tokenizer.Attributes) tokenizer.Attributes {
+	var sanitized tokenizer.Attributes
+	for _, entry := range attributes.Entries() {
+		key := strings.ToLower(entry.Key)
+		if strings.HasPrefix(key, "on") {
+			continue
+		}
+		if _, ok := unsafeAttributes[key]; ok {
+			continue
+		}
+		if _, ok := safeUrlAttributes[key]; ok && !IsSafeUrl(entry.Value) {
+			continue
+		}
+		if key == "style" && !safeStyleAttribute.MatchString(entry.Value) {
+			continue
+		}
+		sanitized.Set(entry.Key, html.EscapeString(entry.Value))
+	}
+	return sanitized
+}
+
+// Safe returns conversion context which is suitable for rendering of untrusted input:
+//   - raw blocks and raw inlines are not rendered (raw inline is rendered as regular verbatim)
+//   - text is always escaped
+//   - attributes are sanitized with SanitizeAttributes
+func Safe(context ConversionContext[*HtmlWriter]) ConversionContext[*HtmlWriter] {
+	registry := make(ConversionRegistry[*HtmlWriter], len(context.Registry))
+	for node, conversion := range context.Registry {
+		registry[node] = func(state ConversionState[*HtmlWriter], next func(Children)) {
+			state.Node.Attributes = SanitizeAttributes(state.Node.Attributes)
+			if _, ok := state.Node.Attributes.TryGet(RawInlineFormatKey); ok {
+				state.Node.Attributes = removeAttribute(state.Node.Attributes, RawInlineFormatKey)
+			}
+			conversion(state, next)
+		}
+	}
+	if _, ok := registry[RawNode]; ok {
+		registry[RawNode] = func(state ConversionState[*HtmlWriter], next func(Children)) {}
+	}
+	if conversion, ok := registry[TextNode]; ok {
+		registry[TextNode] = func(state ConversionState[*HtmlWriter], next func(Children)) {
+			// text is rendered unescaped only inside raw nodes which are detected by the parent attributes
+			state.Parent = nil
+			conversion(state, next)
+		}
+	}
+	context.Registry = registry
+	return context
+}
+
+func removeAttribute(attributes tokenizer.Attributes, key string) tokenizer.Attributes {
+	var result tokenizer.Attributes
+	for _, entry := range attributes.Entries() {
+		if entry.Key != key {
+			result.Set(entry.Key, entry.Value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,195 @@
+package djot_html
+
+import (
+	"strconv"
+	"strings"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// SidenoteConversionRegistry renders footnotes as Tufte-style sidenotes (with checkbox toggle for narrow screens) and omits
+// document endnotes section: reference is rendered as the toggle and footnote content is rendered in <aside class="sidenote">
+// block right after the block with the reference (sidenote can contain blocks, so it can't be placed inside the paragraph);
+// document must be parsed with Options.AttachFootnotes
+//
+//	djot_html.New(djot_html.DefaultConversionRegistry, djot_html.SidenoteConversionRegistry)
+var SidenoteConversionRegistry = map[DjotNode]Conversion[*HtmlWriter]{
+	DocumentNode: func(s ConversionState[*HtmlWriter], n func(c Children)) { n(withSidenotes(s.Node.Children)) },
+	FootnoteReferenceNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
+		id := sidenoteId(s.Node)
+		s.Writer.
+			InTag("label", tokenizer.AttributeEntry{Key: "for", Value: id}, tokenizer.AttributeEntry{Key: "class", Value: "margin-toggle sidenote-number"})(func() {}).
+			OpenTag("input", tokenizer.AttributeEntry{Key: "type", Value: "checkbox"}, tokenizer.AttributeEntry{Key: "id", Value: id}, tokenizer.AttributeEntry{Key: "class", Value: "margin-toggle"})
+	},
+	FootnoteDefNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
+		if s.Node.Attributes.Get(RoleKey) == "doc-footnote" {
+			BlockNodeConverter(s, "aside", n)
+		} else {
+			n(nil)
+		}
+	},
+	SectionNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
+		if !isEndnotesSection(s.Node) {
+			DefaultConversionRegistry[SectionNode](s, n)
+		}
+	},
+}
+
+func sidenoteId(reference TreeNode[DjotNode]) string {
+	return reference.Children[0].Attributes.Get(IdKey) + "-sidenote"
+}
+
+// isSidenoteContainer returns true for nodes which children are blocks (or inline content of the tight list item)
+func isSidenoteContainer(node DjotNode) bool {
+	switch node {
+	case DocumentNode, SectionNode, QuoteNode, DivNode, ListItemNode, UnorderedListNode, OrderedListNode, TaskListNode:
+		return true
+	}
+	return false
+}
+
+// withSidenotes inserts content of the footnotes after the blocks which contain references to them
+// (sidenotes for the inline content of the tight list item and for the nodes of unknown kind are inserted after the whole run of such nodes)
+func withSidenotes(nodes Children) Children {
+	result := make(Children, 0, len(nodes))
+	var pending Children
+	for _, node := range nodes {
+		switch {
+		case isSidenoteContainer(node.Type):
+			result = append(result, pending...)
+			pending = nil
+			node.Children = withSidenotes(node.Children)
+			result = append(result, node)
+		case node.Type.IsBlock():
+			result = append(result, pending...)
+			pending = nil
+			result = append(append(result, node), sidenotes(node)...)
+		default:
+			result = append(result, node)
+			pending = append(pending, sidenotes(node)...)
+		}
+	}
+	return append(result, pending...)
+}
+
+// sidenotes returns footnotes content for all references from the node subtree
+func sidenotes(node TreeNode[DjotNode]) Children {
+	var notes Children
+	if node.Type == FootnoteReferenceNode {
+		return append(notes, TreeNode[DjotNode]{
+			Type:     FootnoteDefNode,
+			Children: node.Children[1].Children,
+			Attributes: tokenizer.NewAttributes(
+				tokenizer.AttributeEntry{Key: "class", Value: "sidenote"},
+				tokenizer.AttributeEntry{Key: RoleKey, Value: "doc-footnote"},
+			),
+		})
+	}
+	for _, child := range node.Children {
+		notes = append(notes, sidenotes(child)...)
+	}
+	return notes
+}
+
+// SectionEndnotesConversionRegistry renders every footnote once at the end of the section where it was referenced first
+// (footnotes first referenced outside of sections are rendered at the end of the document) and omits document endnotes section;
+// document must be parsed with Options.AttachFootnotes
+var SectionEndnotesConversionRegistry = map[DjotNode]Conversion[*HtmlWriter]{
+	DocumentNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
+		n(withSectionEndnotes(s.Node, collectFootnoteBacklinks(s.Node, make(map[string][]string))).Children)
+	},
+	SectionNode: func(s ConversionState[*HtmlWriter], n func(c Children)) {
+		if isEndnotesSection(s.Node) {
+			BlockNodeConverter(s, "aside", n)
+		} else {
+			DefaultConversionRegistry[SectionNode](s, n)
+		}
+	},
+}
+
+func isEndnotesSection(node TreeNode[DjotNode]) bool {
+	return node.Type == SectionNode && node.Attributes.Get(RoleKey) == "doc-endnotes"
+}
+
+// collectFootnoteBacklinks returns ids of all references for every footnote number (in the document order)
+func collectFootnoteBacklinks(node TreeNode[DjotNode], backlinks map[string][]string) map[string][]string {
+	for _, child := range node.Children {
+		if child.Type == FootnoteReferenceNode {
+			number := child.Attributes.Get(FootnoteNumberKey)
+			backlinks[number] = append(backlinks[number], child.Children[0].Attributes.Get(IdKey))
+			continue
+		}
+		collectFootnoteBacklinks(child, backlinks)
+	}
+	return backlinks
+}
+
+// withSectionEndnotes appends endnotes to the node and all nested sections: footnote is rendered in the section which contains its
+// first reference (footnotes are removed from backlinks after that, so every footnote is rendered once); original endnotes section is removed
+func withSectionEndnotes(node TreeNode[DjotNode], backlinks map[string][]string) TreeNode[DjotNode] {
+	footnotes := SectionFootnotes(node)
+	children := make(Children, 0, len(node.Children)+1)
+	for _, child := range node.Children {
+		if isEndnotesSection(child) {
+			continue
+		}
+		children = append(children, child)
+	}
+	items := make(Children, 0, len(footnotes))
+	for _, footnote := range footnotes {
+		number := footnote.Attributes.Get(FootnoteNumberKey)
+		if references, ok := backlinks[number]; ok {
+			delete(backlinks, number)
+			items = append(items, buildSectionEndnote(footnote, references))
+		}
+	}
+	for i, child := range children {
+		if child.Type == SectionNode {
+			children[i] = withSectionEndnotes(child, backlinks)
+		}
+	}
+	if len(items) > 0 {
+		children = append(children, TreeNode[DjotNode]{
+			Type:       SectionNode,
+			Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: RoleKey, Value: "doc-endnotes"}),
+			Children:   Children{{Type: OrderedListNode, Children: items}},
+		})
+	}
+	node.Children = children
+	return node
+}
+
+// buildSectionEndnote creates endnotes list item with the backlinks to all references of the footnote
+func buildSectionEndnote(footnote TreeNode[DjotNode], references []string) TreeNode[DjotNode] {
+	link, definition := footnote.Children[0], footnote.Children[1]
+	children := append(Children{}, definition.Children...)
+	backlinks := make(Children, 0, len(references))
+	for i, reference := range references {
+		text := Children{{Type: TextNode, Text: []byte("↩︎︎")}}
+		if i > 0 {
+			text = append(text, TreeNode[DjotNode]{Type: SuperscriptNode, Children: Children{{Type: TextNode, Text: []byte(strconv.Itoa(i + 1))}}})
+		}
+		backlinks = append(backlinks, TreeNode[DjotNode]{
+			Type:     LinkNode,
+			Children: text,
+			Attributes: tokenizer.NewAttributes(
+				tokenizer.AttributeEntry{Key: LinkHrefKey, Value: "#" + reference},
+				tokenizer.AttributeEntry{Key: RoleKey, Value: "doc-backlink"},
+			),
+		})
+	}
+	if last := len(children) - 1; last >= 0 && children[last].Type == ParagraphNode {
+		children[last].Children = append(append(Children{}, children[last].Children...), backlinks...)
+	} else {
+		children = append(children, TreeNode[DjotNode]{Type: ParagraphNode, Children: backlinks})
+	}
+	return TreeNode[DjotNode]{
+		Type:     ListItemNode,
+		Children: Children{{Type: FootnoteDefNode, Children: children}},
+		Attributes: tokenizer.NewAttributes(
+			tokenizer.AttributeEntry{Key: IdKey, Value: strings.TrimPrefix(link.Attributes.Get(LinkHrefKey), "#")},
+			tokenizer.AttributeEntry{Key: "value", Value: footnote.Attributes.Get(FootnoteNumberKey)},
+		),
+	}
+}
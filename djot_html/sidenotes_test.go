@@ -0,0 +1,135 @@
+package djot_html
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestAttachedFootnotes(t *testing.T) {
+	djot := []byte(`Intro[^a].
+
+# First
+
+Text[^b] and again[^b].
+
+# Second
+
+More[^a].
+
+[^a]: Note *A*
+
+[^b]: Note B
+
+  Second paragraph
+`)
+	document := Options{AttachFootnotes: true}.BuildDjotDocument(djot)
+	t.Run("default", func(t *testing.T) {
+		require.Equal(t,
+			New().ConvertDjotDocument(&HtmlWriter{}, Options{}.BuildDjotDocument(djot)).String(),
+			New().ConvertDjotDocument(&HtmlWriter{}, document).String(),
+		)
+	})
+	t.Run("sidenotes", func(t *testing.T) {
+		require.Equal(t, `<p>Intro<label class="margin-toggle sidenote-number" for="fnref1-sidenote"></label><input class="margin-toggle" id="fnref1-sidenote" type="checkbox">.</p>
+<aside class="sidenote" role="doc-footnote">
+<p>Note <strong>A</strong></p>
+</aside>
+<section id="First">
+<h1>First</h1>
+<p>Text<label class="margin-toggle sidenote-number" for="fnref2-sidenote"></label><input class="margin-toggle" id="fnref2-sidenote" type="checkbox"> and again<label class="margin-toggle sidenote-number" for="fnref2-2-sidenote"></label><input class="margin-toggle" id="fnref2-2-sidenote" type="checkbox">.</p>
+<aside class="sidenote" role="doc-footnote">
+<p>Note B</p>
+<p>Second paragraph</p>
+</aside>
+<aside class="sidenote" role="doc-footnote">
+<p>Note B</p>
+<p>Second paragraph</p>
+</aside>
+</section>
+<section id="Second">
+<h1>Second</h1>
+<p>More<label class="margin-toggle sidenote-number" for="fnref1-2-sidenote"></label><input class="margin-toggle" id="fnref1-2-sidenote" type="checkbox">.</p>
+<aside class="sidenote" role="doc-footnote">
+<p>Note <strong>A</strong></p>
+</aside>
+</section>
+`, New(DefaultConversionRegistry, SidenoteConversionRegistry).ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+	t.Run("section endnotes", func(t *testing.T) {
+		require.Equal(t, `<p>Intro<a id="fnref1" href="#fn1" role="doc-noteref"><sup>1</sup></a>.</p>
+<section id="First">
+<h1>First</h1>
+<p>Text<a id="fnref2" href="#fn2" role="doc-noteref"><sup>2</sup></a> and again<a id="fnref2-2" href="#fn2" role="doc-noteref"><sup>2</sup></a>.</p>
+<aside role="doc-endnotes">
+<ol>
+<li id="fn2" value="2">
+<p>Note B</p>
+<p>Second paragraph<a href="#fnref2" role="doc-backlink">↩︎︎</a><a href="#fnref2-2" role="doc-backlink">↩︎︎<sup>2</sup></a></p>
+</li>
+</ol>
+</aside>
+</section>
+<section id="Second">
+<h1>Second</h1>
+<p>More<a id="fnref1-2" href="#fn1" role="doc-noteref"><sup>1</sup></a>.</p>
+</section>
+<aside role="doc-endnotes">
+<ol>
+<li id="fn1" value="1">
+<p>Note <strong>A</strong><a href="#fnref1" role="doc-backlink">↩︎︎</a><a href="#fnref1-2" role="doc-backlink">↩︎︎<sup>2</sup></a></p>
+</li>
+</ol>
+</aside>
+`, New(DefaultConversionRegistry, SectionEndnotesConversionRegistry).ConvertDjotDocument(&HtmlWriter{}, document).String())
+	})
+}
+
+func TestSidenotesInsideLists(t *testing.T) {
+	document := Options{AttachFootnotes: true}.BuildDjotDocument([]byte("- item[^a]\n- other\n\n[^a]: note\n"))
+	require.Equal(t, `<ul>
+<li>
+item<label class="margin-toggle sidenote-number" for="fnref1-sidenote"></label><input class="margin-toggle" id="fnref1-sidenote" type="checkbox">
+<aside class="sidenote" role="doc-footnote">
+<p>note</p>
+</aside>
+</li>
+<li>
+other
+</li>
+</ul>
+`, New(DefaultConversionRegistry, SidenoteConversionRegistry).ConvertDjotDocument(&HtmlWriter{}, document).String())
+}
+
+var sidenoteTestNode = NewDjotNode("SidenoteTestNode")
+
+func TestSidenotesUserNodes(t *testing.T) {
+	reference := Options{AttachFootnotes: true}.BuildDjotDocument([]byte("a[^a]\n\n[^a]: note\n")).Nodes[0].Children[0].Children[1]
+	require.Equal(t, FootnoteReferenceNode, reference.Type)
+	text := func(value string) TreeNode[DjotNode] { return TreeNode[DjotNode]{Type: TextNode, Text: []byte(value)} }
+	document := TreeNode[DjotNode]{Type: DocumentNode, Children: Children{
+		{Type: UnorderedListNode, Children: Children{
+			{Type: ListItemNode, Children: Children{text("item"), {Type: sidenoteTestNode, Children: Children{reference}}, text(" end")}},
+		}},
+		{Type: sidenoteTestNode, Children: Children{{Type: ParagraphNode, Children: Children{text("block"), reference}}}},
+		{Type: ParagraphNode, Children: Children{text("after")}},
+	}}
+	context := New(DefaultConversionRegistry, SidenoteConversionRegistry, map[DjotNode]Conversion[*HtmlWriter]{
+		sidenoteTestNode: func(s ConversionState[*HtmlWriter], n func(c Children)) { s.Writer.InTag("custom")(func() { n(nil) }) },
+	})
+	require.Equal(t, `<ul>
+<li>
+item<custom><label class="margin-toggle sidenote-number" for="fnref1-sidenote"></label><input class="margin-toggle" id="fnref1-sidenote" type="checkbox"></custom> end<aside class="sidenote" role="doc-footnote">
+<p>note</p>
+</aside>
+</li>
+</ul>
+<custom><p>block<label class="margin-toggle sidenote-number" for="fnref1-sidenote"></label><input class="margin-toggle" id="fnref1-sidenote" type="checkbox"></p>
+</custom><aside class="sidenote" role="doc-footnote">
+<p>note</p>
+</aside>
+<p>after</p>
+`, context.ConvertDjotDocument(&HtmlWriter{}, DjotDocument{Nodes: Children{document}}).String())
+}
@@ -0,0 +1,32 @@
+package djot_html
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestSmartPunctuation(t *testing.T) {
+	djot := []byte(`"Don't" -- 'quote'... ---`)
+	for _, tt := range []struct {
+		name        string
+		punctuation SmartPunctuation
+		mode        EntityMode
+		html        string
+	}{
+		{name: "default", html: "<p>&ldquo;Don&rsquo;t&rdquo; &ndash; &lsquo;quote&rsquo;&hellip; &mdash;</p>\n"},
+		{name: "disabled", punctuation: SmartPunctuation{Disabled: true}, html: "<p>\"Don't\" -- 'quote'... ---</p>\n"},
+		{name: "german", punctuation: SmartPunctuation{Quotes: GermanQuotes}, mode: Utf8Characters, html: "<p>„Don’t“ – ‚quote‘… —</p>\n"},
+		{name: "french", punctuation: SmartPunctuation{Quotes: FrenchQuotes}, mode: Utf8Characters, html: "<p>«\u202FDon’t\u202F» – ‹\u202Fquote\u202F›… —</p>\n"},
+		{name: "french named", punctuation: SmartPunctuation{Quotes: FrenchQuotes}, html: "<p>&laquo;&#8239;Don&rsquo;t&#8239;&raquo; &ndash; &lsaquo;&#8239;quote&#8239;&rsaquo;&hellip; &mdash;</p>\n"},
+		{name: "swedish numeric", punctuation: SmartPunctuation{Quotes: SwedishQuotes}, mode: NumericEntities, html: "<p>&#8221;Don&#8217;t&#8221; &#8211; &#8217;quote&#8217;&#8230; &#8212;</p>\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			document := Options{SmartPunctuation: tt.punctuation}.BuildDjotDocument(djot)
+			context := New(DefaultConversionRegistry, EntityConversionRegistry(tt.mode))
+			require.Equal(t, tt.html, context.ConvertDjotDocument(&HtmlWriter{}, document).String())
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package djot_html
+
+import (
+	"fmt"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// SourcePosKey is the attribute with the source position of the block element: "startLine:startColumn-endLine:endColumn"
+// (lines and columns are one-based, columns are measured in bytes and the end position points to the last character of the block)
+const SourcePosKey = "data-sourcepos"
+
+// SourcePositionNodes are block-level nodes which get SourcePosKey attribute in the SourcePositions mode
+var SourcePositionNodes = []DjotNode{
+	ParagraphNode,
+	HeadingNode,
+	QuoteNode,
+	ListItemNode,
+	DefinitionTermNode,
+	DefinitionItemNode,
+	TableRowNode,
+	CodeNode,
+	DivNode,
+}
+
+// SourcePositions returns conversion context which adds SourcePosKey attribute to the SourcePositionNodes
+// (document must be parsed with Options.SourcePositions and converted with ConvertDjotDocument); it's useful for scroll sync of the preview
+func SourcePositions(context ConversionContext[*HtmlWriter]) ConversionContext[*HtmlWriter] {
+	registry := make(ConversionRegistry[*HtmlWriter], len(context.Registry))
+	for node, conversion := range context.Registry {
+		registry[node] = conversion
+	}
+	for _, node := range SourcePositionNodes {
+		conversion, ok := registry[node]
+		if !ok {
+			continue
+		}
+		registry[node] = func(state ConversionState[*HtmlWriter], next func(Children)) {
+			if start, end, ok := state.SourceSpan(); ok {
+				// node attributes can be shared with the AST, so they are copied before modification
+				attributes := tokenizer.NewAttributes(state.Node.Attributes.Entries()...)
+				attributes.Set(SourcePosKey, fmt.Sprintf("%v:%v-%v:%v", start.Line+1, start.Column+1, end.Line+1, max(end.Column, 1)))
+				state.Node.Attributes = attributes
+			}
+			conversion(state, next)
+		}
+	}
+	context.Registry = registry
+	return context
+}
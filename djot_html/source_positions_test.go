@@ -0,0 +1,53 @@
+package djot_html
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestSourcePositions(t *testing.T) {
+	djot := []byte("# Title\n\nfirst *para*\ngraph\n\n> quote\n\n- [x] task\n\n  more\n\n: term\n\n  definition\n\n```go\ncode\n```\n\n::: note\ninside\n:::\n\n| a | b |\n|---|---|\n| 1 | 2 |\n")
+	document := Options{SourcePositions: true, DisableSections: true}.BuildDjotDocument(djot)
+	require.Equal(t, `<h1 id="Title" data-sourcepos="1:1-1:7">Title</h1>
+<p data-sourcepos="3:1-4:5">first <strong>para</strong>
+graph</p>
+<blockquote data-sourcepos="6:1-6:7">
+<p data-sourcepos="6:3-6:7">quote</p>
+</blockquote>
+<ul class="task-list">
+<li data-sourcepos="8:1-10:6">
+<input disabled="" type="checkbox" checked=""/>
+<p data-sourcepos="8:7-8:10">task</p>
+<p data-sourcepos="10:3-10:6">more</p>
+</li>
+</ul>
+<dl>
+<dt data-sourcepos="12:1-14:12">term</dt>
+<dd data-sourcepos="12:1-14:12">
+<p data-sourcepos="14:3-14:12">definition</p>
+</dd>
+</dl>
+<pre><code class="language-go" data-sourcepos="16:1-18:3">code
+</code></pre>
+<div class="note" data-sourcepos="20:1-22:3">
+<p data-sourcepos="21:1-21:6">inside</p>
+</div>
+<table>
+<tr data-sourcepos="24:1-24:9">
+<th>a</th>
+<th>b</th>
+</tr>
+<tr data-sourcepos="26:1-26:9">
+<td>1</td>
+<td>2</td>
+</tr>
+</table>
+`, SourcePositions(New()).ConvertDjotDocument(&HtmlWriter{}, document).String())
+	t.Run("without positions", func(t *testing.T) {
+		document := Options{}.BuildDjotDocument(djot)
+		require.NotContains(t, SourcePositions(New()).ConvertDjotDocument(&HtmlWriter{}, document).String(), SourcePosKey)
+	})
+}
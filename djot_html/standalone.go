@@ -0,0 +1,95 @@
+package djot_html
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+//go:embed templates/standalone.html
+var defaultStandaloneTemplate string
+
+// DefaultStandaloneTemplate renders minimal HTML5 document around the converted body (see StandaloneData for available fields)
+var DefaultStandaloneTemplate = template.Must(template.New("standalone").Parse(defaultStandaloneTemplate))
+
+// StandaloneOptions configures RenderStandalone
+//   - Title and Language override values from the document metadata ("title" and "lang" keys); if title is missing completely, text of the first heading is used
+//   - Template is DefaultStandaloneTemplate if not set
+type StandaloneOptions struct {
+	Template    *template.Template
+	Title       string
+	Language    string
+	Stylesheets []string
+	Toc         bool
+}
+
+// StandaloneData is the data passed to the standalone template
+type StandaloneData struct {
+	Title       string
+	Language    string
+	Stylesheets []string
+	Metadata    Metadata
+	Toc         template.HTML
+	Body        template.HTML
+}
+
+func NewStandaloneData(context ConversionContext[*HtmlWriter], document DjotDocument, options StandaloneOptions) StandaloneData {
+	data := StandaloneData{
+		Title:       options.Title,
+		Language:    options.Language,
+		Stylesheets: options.Stylesheets,
+		Metadata:    document.Metadata,
+		Body:        template.HTML(context.ConvertDjotDocument(&HtmlWriter{}, document).String()),
+	}
+	toc := TableOfContents(document.Nodes)
+	if data.Title == "" {
+		data.Title = document.Metadata.String("title")
+	}
+	if data.Title == "" && len(toc) > 0 {
+		data.Title = toc[0].Title
+	}
+	if data.Language == "" {
+		data.Language = document.Metadata.String("lang")
+	}
+	if options.Toc && len(toc) > 0 {
+		data.Toc = template.HTML(ConvertToc(&HtmlWriter{}, toc).String())
+	}
+	return data
+}
+
+// RenderStandalone writes complete HTML document with the converted djot document as its body
+func RenderStandalone(w io.Writer, context ConversionContext[*HtmlWriter], document DjotDocument, options StandaloneOptions) error {
+	tmpl := options.Template
+	if tmpl == nil {
+		tmpl = DefaultStandaloneTemplate
+	}
+	return tmpl.Execute(w, NewStandaloneData(context, document, options))
+}
+
+// ConvertToc renders table of contents as nested lists of links wrapped into the <nav> element
+func ConvertToc(writer *HtmlWriter, toc []TocEntry) *HtmlWriter {
+	var convert func(entries []TocEntry)
+	convert = func(entries []TocEntry) {
+		writer.InTag("ul")(func() {
+			writer.WriteString("\n")
+			for _, entry := range entries {
+				writer.InTag("li")(func() {
+					writer.InTag("a", tokenizer.AttributeEntry{Key: LinkHrefKey, Value: "#" + entry.Id})(func() {
+						writer.WriteString(htmlReplacer.Replace(entry.Title))
+					})
+					if len(entry.Children) > 0 {
+						writer.WriteString("\n")
+						convert(entry.Children)
+					}
+				}).WriteString("\n")
+			}
+		}).WriteString("\n")
+	}
+	return writer.InTag("nav", tokenizer.AttributeEntry{Key: IdKey, Value: "TOC"}, tokenizer.AttributeEntry{Key: RoleKey, Value: "doc-toc"})(func() {
+		writer.WriteString("\n")
+		convert(toc)
+	}).WriteString("\n")
+}
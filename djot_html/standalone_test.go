@@ -0,0 +1,63 @@
+package djot_html
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestRenderStandalone(t *testing.T) {
+	t.Run("default template", func(t *testing.T) {
+		document := Options{FrontMatter: true}.BuildDjotDocument([]byte("---\nauthor: Alice & Bob\nlang: en\n---\n# Intro *x*\n\ntext\n\n## Details\n\n# Summary\n"))
+		var result strings.Builder
+		require.Nil(t, RenderStandalone(&result, New(), document, StandaloneOptions{Toc: true, Stylesheets: []string{"style.css"}}))
+		require.Equal(t, `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Intro x</title>
+<meta name="author" content="Alice &amp; Bob">
+<link rel="stylesheet" href="style.css">
+</head>
+<body>
+<nav id="TOC" role="doc-toc">
+<ul>
+<li><a href="#Intro-x">Intro x</a>
+<ul>
+<li><a href="#Details">Details</a></li>
+</ul>
+</li>
+<li><a href="#Summary">Summary</a></li>
+</ul>
+</nav>
+<section id="Intro-x">
+<h1>Intro <strong>x</strong></h1>
+<p>text</p>
+</section>
+<section id="Details">
+<h2>Details</h2>
+</section>
+<section id="Summary">
+<h1>Summary</h1>
+</section>
+</body>
+</html>
+`, result.String())
+	})
+	t.Run("custom template", func(t *testing.T) {
+		document := Options{FrontMatter: true}.BuildDjotDocument([]byte("---\ntitle: <Metadata title>\n---\n# Heading\n"))
+		tmpl := template.Must(template.New("custom").Parse(`<title>{{.Title}}</title>{{.Body}}`))
+		var result strings.Builder
+		require.Nil(t, RenderStandalone(&result, New(), document, StandaloneOptions{Template: tmpl}))
+		require.Equal(t, "<title>&lt;Metadata title&gt;</title><section id=\"Heading\">\n<h1>Heading</h1>\n</section>\n", result.String())
+
+		data := NewStandaloneData(New(), document, StandaloneOptions{Title: "Explicit"})
+		require.Equal(t, "Explicit", data.Title)
+		require.Equal(t, template.HTML(""), data.Toc)
+	})
+}
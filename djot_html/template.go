@@ -0,0 +1,108 @@
+package djot_html
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"html/template"
+	"strings"
+	"sync"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/djot_text"
+)
+
+// TemplateCacheSize is the maximum number of rendered snippets kept by the functions returned from FuncMap
+const TemplateCacheSize = 1024
+
+// FuncMap returns html/template functions which render djot markup:
+//   - djot renders markup as HTML blocks
+//   - djotInline renders markup without wrapping paragraph (e.g. for titles and table cells)
+//   - djotText renders markup as plain text (e.g. for <title> or meta description)
+//
+// HTML is rendered with the given conversion context (Safe(New()) by default) and results are cached by the input hash
+func FuncMap(context ...ConversionContext[*HtmlWriter]) template.FuncMap {
+	conversion := Safe(New())
+	if len(context) > 0 {
+		conversion = context[0]
+	}
+	textConversion := djot_text.New()
+	cache := newRenderCache(TemplateCacheSize)
+	return template.FuncMap{
+		"djot": func(markup string) template.HTML {
+			return template.HTML(cache.Get("djot", markup, func() string {
+				return conversion.ConvertDjotDocument(&HtmlWriter{}, BuildDjotDocument([]byte(markup))).String()
+			}))
+		},
+		"djotInline": func(markup string) template.HTML {
+			return template.HTML(cache.Get("djotInline", markup, func() string {
+				document := BuildDjotDocument([]byte(markup))
+				if paragraph, ok := singleParagraph(document.Nodes); ok {
+					document.Nodes = paragraph.Children
+				}
+				return strings.TrimSpace(conversion.ConvertDjotDocument(&HtmlWriter{}, document).String())
+			}))
+		},
+		"djotText": func(markup string) string {
+			return cache.Get("djotText", markup, func() string {
+				return strings.TrimSpace(textConversion.ConvertDjotDocument(&djot_text.TextWriter{}, BuildDjotDocument([]byte(markup))).String())
+			})
+		},
+	}
+}
+
+func singleParagraph(nodes []TreeNode[DjotNode]) (TreeNode[DjotNode], bool) {
+	for len(nodes) == 1 && nodes[0].Type != ParagraphNode {
+		nodes = nodes[0].Children
+	}
+	if len(nodes) != 1 {
+		return TreeNode[DjotNode]{}, false
+	}
+	return nodes[0], true
+}
+
+type renderCacheKey struct {
+	kind string
+	hash [sha256.Size]byte
+}
+
+type renderCacheEntry struct {
+	key    renderCacheKey
+	result string
+}
+
+// renderCache is the LRU cache of rendered snippets which is safe for concurrent use
+type renderCache struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[renderCacheKey]*list.Element
+}
+
+func newRenderCache(capacity int) *renderCache {
+	return &renderCache{capacity: capacity, order: list.New(), entries: make(map[renderCacheKey]*list.Element)}
+}
+
+func (c *renderCache) Get(kind, markup string, render func() string) string {
+	key := renderCacheKey{kind: kind, hash: sha256.Sum256([]byte(markup))}
+	c.mutex.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		c.mutex.Unlock()
+		return element.Value.(renderCacheEntry).result
+	}
+	c.mutex.Unlock()
+
+	result := render()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(renderCacheEntry{key: key, result: result})
+		if c.order.Len() > c.capacity {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(renderCacheEntry).key)
+		}
+	}
+	return result
+}
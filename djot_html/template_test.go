@@ -0,0 +1,59 @@
+package djot_html
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestSafe(t *testing.T) {
+	for _, tt := range []struct{ djot, html string }{
+		{djot: "[click](javascript:alert`1`)", html: "<p><a>click</a></p>\n"},
+		{djot: "![img](data:text/html,hello)", html: "<p><img alt=\"img\"></p>\n"},
+		{djot: "![img](data:image/png;base64,AAAA)", html: "<p><img alt=\"img\" src=\"data:image/png;base64,AAAA\"></p>\n"},
+		{djot: "{onclick=\"alert(1)\" style=\"color: red\" class=x}\ntext", html: "<p class=\"x\">text</p>\n"},
+		{djot: "``` =html\n<script>alert(1)</script>\n```", html: ""},
+		{djot: "`<b>`{=html}", html: "<p><code>&lt;b&gt;</code></p>\n"},
+		{djot: "[ok](https://example.com)", html: "<p><a href=\"https://example.com\">ok</a></p>\n"},
+	} {
+		t.Run(tt.djot, func(t *testing.T) {
+			ast := djot_parser.BuildDjotAst([]byte(tt.djot))
+			require.Equal(t, tt.html, Safe(New()).ConvertDjot(&HtmlWriter{}, ast...).String())
+		})
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	page := template.Must(template.New("page").Funcs(FuncMap()).Parse(
+		`<title>{{ djotText .Title }}</title><h1>{{ djotInline .Title }}</h1>{{ djot .Body }}`,
+	))
+	var output strings.Builder
+	require.Nil(t, page.Execute(&output, map[string]string{
+		"Title": "*Hello*, `world`",
+		"Body":  "text `<script>`{=html}",
+	}))
+	require.Equal(t,
+		"<title>Hello, world</title><h1><strong>Hello</strong>, <code>world</code></h1><p>text <code>&lt;script&gt;</code></p>\n",
+		output.String(),
+	)
+}
+
+func TestRenderCache(t *testing.T) {
+	cache := newRenderCache(2)
+	renders := 0
+	render := func() string { renders++; return "result" }
+	require.Equal(t, "result", cache.Get("djot", "a", render))
+	require.Equal(t, "result", cache.Get("djot", "a", render))
+	require.Equal(t, 1, renders)
+	cache.Get("djotText", "a", render)
+	cache.Get("djot", "b", render)
+	require.Equal(t, 3, renders)
+	cache.Get("djotText", "a", render)
+	require.Equal(t, 3, renders)
+	cache.Get("djot", "a", render)
+	require.Equal(t, 4, renders)
+}
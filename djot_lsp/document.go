@@ -0,0 +1,320 @@
+package djot_lsp
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+type document struct {
+	text   []byte
+	lines  lineIndex
+	parsed djot_parser.DjotDocument
+	index  djot_parser.SourceIndex
+	// idPrefix is stripped from the heading ids because heading references use ids without prefix
+	idPrefix string
+}
+
+func newDocument(parser *djot_parser.Parser, text []byte) *document {
+	parsed := parser.Parse(text)
+	return &document{text: text, lines: newLineIndex(text), parsed: parsed, index: parsed.Index(), idPrefix: parser.Options().IdPrefix}
+}
+
+func (d *document) diagnostics() []diagnostic {
+	diagnostics := make([]diagnostic, 0, len(d.parsed.Diagnostics))
+	for _, item := range d.parsed.Diagnostics {
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    d.lines.lspRange(item.Range),
+			Severity: int(item.Severity),
+			Source:   "godjot",
+			Message:  item.Message,
+		})
+	}
+	return diagnostics
+}
+
+// sectionEnd returns the end offset of the section started by the i-th heading
+// (start of the next heading with the same or higher level or the end of the document)
+func (d *document) sectionEnd(i int) int {
+	for _, heading := range d.index.Headings[i+1:] {
+		if heading.Level <= d.index.Headings[i].Level {
+			return heading.Range.Start
+		}
+	}
+	return len(d.text)
+}
+
+func (d *document) symbols() []documentSymbol {
+	root := documentSymbol{}
+	// stack holds path from the root to the last added symbol together with the levels of the headings
+	stack, levels := []*documentSymbol{&root}, []int{0}
+	for i, heading := range d.index.Headings {
+		for levels[len(levels)-1] >= heading.Level {
+			stack, levels = stack[:len(stack)-1], levels[:len(levels)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.Children = append(parent.Children, documentSymbol{
+			Name:           heading.Title,
+			Detail:         "#" + heading.Id,
+			Kind:           symbolKindString,
+			Range:          d.lines.lspRange(tokenizer.Range{Start: heading.Range.Start, End: d.sectionEnd(i)}),
+			SelectionRange: d.lines.lspRange(heading.Range),
+		})
+		stack, levels = append(stack, &parent.Children[len(parent.Children)-1]), append(levels, heading.Level)
+	}
+	if root.Children == nil {
+		return []documentSymbol{}
+	}
+	return root.Children
+}
+
+type symbolKind int
+
+const (
+	linkSymbol symbolKind = iota + 1
+	footnoteSymbol
+	headingSymbol
+)
+
+// symbolAt returns kind and label of the reference, definition or heading under the cursor
+func (d *document) symbolAt(offset int) (symbolKind, string, tokenizer.Range, bool) {
+	contains := func(r tokenizer.Range) bool { return r.Start <= offset && offset < r.End }
+	for _, group := range []struct {
+		kind    symbolKind
+		symbols []djot_parser.SourceSymbol
+	}{
+		{footnoteSymbol, d.index.FootnoteReferences},
+		{linkSymbol, d.index.LinkReferences},
+		{footnoteSymbol, d.index.FootnoteDefinitions},
+		{linkSymbol, d.index.LinkDefinitions},
+	} {
+		for _, symbol := range group.symbols {
+			if contains(symbol.Range) {
+				return group.kind, symbol.Label, symbol.Range, true
+			}
+		}
+	}
+	for _, heading := range d.index.Headings {
+		if contains(heading.Range) {
+			return headingSymbol, heading.Title, heading.Range, true
+		}
+	}
+	return 0, "", tokenizer.Range{}, false
+}
+
+// headingMatches returns true if link reference label refers to the heading implicitly (by its text or id)
+func (d *document) headingMatches(heading djot_parser.HeadingSymbol, label string) bool {
+	return heading.Title == label || strings.TrimPrefix(heading.Id, d.idPrefix) == label
+}
+
+// definitions returns ranges of the definitions for the link or footnote label
+func (d *document) definitions(kind symbolKind, label string) []tokenizer.Range {
+	var ranges []tokenizer.Range
+	switch kind {
+	case footnoteSymbol:
+		for _, definition := range d.index.FootnoteDefinitions {
+			if definition.Label == label {
+				ranges = append(ranges, definition.Range)
+			}
+		}
+	case linkSymbol:
+		for _, definition := range d.index.LinkDefinitions {
+			if definition.Label == label {
+				ranges = append(ranges, definition.Range)
+			}
+		}
+		if len(ranges) > 0 {
+			break
+		}
+		for _, heading := range d.index.Headings {
+			if d.headingMatches(heading, label) {
+				return []tokenizer.Range{heading.Range}
+			}
+		}
+	}
+	return ranges
+}
+
+func (d *document) locations(uri string, ranges []tokenizer.Range) []location {
+	locations := make([]location, 0, len(ranges))
+	for _, r := range ranges {
+		locations = append(locations, location{Uri: uri, Range: d.lines.lspRange(r)})
+	}
+	return locations
+}
+
+func (d *document) definition(uri string, offset int) []location {
+	kind, label, _, ok := d.symbolAt(offset)
+	if !ok || kind == headingSymbol {
+		return []location{}
+	}
+	return d.locations(uri, d.definitions(kind, label))
+}
+
+func (d *document) references(uri string, offset int, includeDeclaration bool) []location {
+	kind, label, symbolRange, ok := d.symbolAt(offset)
+	if !ok {
+		return []location{}
+	}
+	var ranges []tokenizer.Range
+	switch kind {
+	case footnoteSymbol:
+		for _, reference := range d.index.FootnoteReferences {
+			if reference.Label == label {
+				ranges = append(ranges, reference.Range)
+			}
+		}
+	case linkSymbol, headingSymbol:
+		var heading *djot_parser.HeadingSymbol
+		for i := range d.index.Headings {
+			if d.index.Headings[i].Range == symbolRange {
+				heading = &d.index.Headings[i]
+			}
+		}
+		for _, reference := range d.index.LinkReferences {
+			if reference.Label == label || (heading != nil && d.headingMatches(*heading, reference.Label)) {
+				ranges = append(ranges, reference.Range)
+			}
+		}
+	}
+	if includeDeclaration {
+		if kind == headingSymbol {
+			ranges = append(ranges, symbolRange)
+		} else {
+			ranges = append(ranges, d.definitions(kind, label)...)
+		}
+	}
+	slices.SortFunc(ranges, func(a, b tokenizer.Range) int { return a.Start - b.Start })
+	return d.locations(uri, ranges)
+}
+
+func (d *document) hover(offset int) (hover, bool) {
+	kind, label, symbolRange, ok := d.symbolAt(offset)
+	if !ok {
+		return hover{}, false
+	}
+	var value string
+	switch kind {
+	case linkSymbol:
+		link, ok := d.parsed.Context.References[label]
+		if !ok {
+			return hover{}, false
+		}
+		value = string(link)
+	case footnoteSymbol:
+		definitions := d.definitions(kind, label)
+		if len(definitions) == 0 {
+			return hover{}, false
+		}
+		definition := definitions[len(definitions)-1]
+		content := d.text[definition.Start:definition.End]
+		if i := bytes.Index(content, []byte("]:")); i >= 0 {
+			content = content[i+2:]
+		}
+		value = string(bytes.TrimSpace(content))
+	default:
+		return hover{}, false
+	}
+	return hover{Contents: markupContent{Kind: "plaintext", Value: value}, Range: d.lines.lspRange(symbolRange)}, true
+}
+
+// completion suggests footnote labels after [^, reference labels after ][ and heading ids after ](#
+func (d *document) completion(offset int) []completionItem {
+	prefix := d.text[d.lines.starts[d.lines.line(offset)]:offset]
+	items := make([]completionItem, 0)
+	seen := make(map[string]struct{})
+	add := func(label, detail string) {
+		if _, ok := seen[label]; ok || label == "" {
+			return
+		}
+		seen[label] = struct{}{}
+		items = append(items, completionItem{Label: label, Kind: completionItemKindReference, Detail: detail})
+	}
+	opened := func(open string, close byte) bool {
+		i := bytes.LastIndex(prefix, []byte(open))
+		return i >= 0 && bytes.IndexByte(prefix[i+len(open):], close) < 0
+	}
+	switch {
+	case opened("[^", ']'):
+		for _, definition := range d.index.FootnoteDefinitions {
+			add(definition.Label, "footnote")
+		}
+	case opened("][", ']'):
+		for _, definition := range d.index.LinkDefinitions {
+			add(definition.Label, string(d.parsed.Context.References[definition.Label]))
+		}
+		for _, heading := range d.index.Headings {
+			add(heading.Title, "#"+heading.Id)
+		}
+	case opened("](#", ')'):
+		for _, heading := range d.index.Headings {
+			add(heading.Id, heading.Title)
+		}
+		for _, id := range d.index.Ids {
+			add(id.Label, "")
+		}
+	}
+	return items
+}
+
+// foldableBlocks are the blocks which can be folded in addition to the heading sections
+var foldableBlocks = []djot_tokenizer.DjotToken{
+	djot_tokenizer.QuoteBlock,
+	djot_tokenizer.ListItemBlock,
+	djot_tokenizer.CodeBlock,
+	djot_tokenizer.DivBlock,
+	djot_tokenizer.PipeTableBlock,
+	djot_tokenizer.FootnoteDefBlock,
+	djot_tokenizer.MetadataBlock,
+}
+
+func (d *document) foldingRanges(parser *djot_parser.Parser) []foldingRange {
+	ranges := make([]foldingRange, 0)
+	add := func(r tokenizer.Range) {
+		// range end points to the start of the next block which is usually on the next line
+		start, end := d.lines.line(r.Start), d.lines.line(max(r.Start, r.End-1))
+		if end > start {
+			ranges = append(ranges, foldingRange{StartLine: start, EndLine: end})
+		}
+	}
+	for i, heading := range d.index.Headings {
+		add(tokenizer.Range{Start: heading.Range.Start, End: d.sectionEnd(i)})
+	}
+	for open, close := range parser.Tokens(d.text).Pairs() {
+		if slices.Contains(foldableBlocks, open.Type) {
+			add(tokenizer.Range{Start: open.Start, End: close.End})
+		}
+	}
+	return ranges
+}
+
+func (d *document) formatting(parser *djot_parser.Parser) []textEdit {
+	formatted := Format(d.text, parser.Tokens(d.text))
+	if bytes.Equal(formatted, d.text) {
+		return []textEdit{}
+	}
+	return []textEdit{{Range: d.lines.lspRange(tokenizer.Range{Start: 0, End: len(d.text)}), NewText: string(formatted)}}
+}
+
+func (d *document) semanticTokens(parser *djot_parser.Parser) []int {
+	types := make(map[djot_parser.SemanticTokenType]int, len(djot_parser.SemanticTokenTypes))
+	for i, tokenType := range djot_parser.SemanticTokenTypes {
+		types[tokenType] = i
+	}
+	tokens := parser.SemanticTokens(d.text)
+	data := make([]int, 0, 5*len(tokens))
+	previous := djot_parser.SourcePosition{}
+	for _, token := range tokens {
+		start := token.Start.Utf16Column
+		if token.Start.Line == previous.Line {
+			start -= previous.Utf16Column
+		}
+		data = append(data, token.Start.Line-previous.Line, start, token.End.Utf16Column-token.Start.Utf16Column, types[token.Type], 0)
+		previous = token.Start
+	}
+	return data
+}
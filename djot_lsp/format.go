@@ -0,0 +1,60 @@
+package djot_lsp
+
+import (
+	"bytes"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// Format normalizes whitespace of the djot document without changing its meaning: it removes trailing whitespace of the lines,
+// collapses consecutive blank lines and leaves single newline at the end of the document.
+// Content of code blocks, metadata and inline verbatim is kept as is
+func Format(document []byte, tokens tokenizer.TokenList[djot_tokenizer.DjotToken]) []byte {
+	var blocks, inlines []tokenizer.Range
+	for open, close := range tokens.Pairs() {
+		switch open.Type {
+		case djot_tokenizer.CodeBlock, djot_tokenizer.MetadataBlock:
+			blocks = append(blocks, tokenizer.Range{Start: open.Start, End: close.End})
+		case djot_tokenizer.VerbatimInline:
+			inlines = append(inlines, tokenizer.Range{Start: open.Start, End: close.End})
+		}
+	}
+	verbatim := func(start, end int) bool {
+		for _, r := range blocks {
+			if r.Start <= start && start < r.End {
+				return true
+			}
+		}
+		for _, r := range inlines {
+			if r.Start < end && end < r.End {
+				return true
+			}
+		}
+		return false
+	}
+
+	formatted := make([]byte, 0, len(document)+1)
+	blank, offset := false, 0
+	for _, line := range bytes.SplitAfter(document, []byte("\n")) {
+		start := offset
+		offset += len(line)
+		if len(line) == 0 {
+			continue
+		}
+		content := bytes.TrimRight(line, "\r\n\t ")
+		if verbatim(start, start+len(content)) {
+			content = bytes.TrimSuffix(line, []byte("\n"))
+		} else if len(content) == 0 {
+			blank = len(formatted) > 0
+			continue
+		}
+		if blank {
+			formatted = append(formatted, '\n')
+			blank = false
+		}
+		formatted = append(formatted, content...)
+		formatted = append(formatted, '\n')
+	}
+	return formatted
+}
@@ -0,0 +1,74 @@
+package djot_lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// JSON-RPC error codes used by the server
+const (
+	parseErrorCode     = -32700
+	invalidParamsCode  = -32602
+	methodNotFoundCode = -32601
+	requestFailedCode  = -32803
+)
+
+type message struct {
+	Jsonrpc string           `json:"jsonrpc"`
+	Id      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string { return fmt.Sprintf("%v (code %v)", e.Message, e.Code) }
+
+// maxContentLength bounds the size of the single message, so client can't force arbitrary allocation
+const maxContentLength = 64 << 20
+
+// readMessage reads single message framed with the base protocol headers (only Content-Length header is used)
+func readMessage(reader *bufio.Reader) (message, error) {
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return message{}, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return message{}, fmt.Errorf("invalid Content-Length header: %w", err)
+	}
+	if length < 0 || length > maxContentLength {
+		return message{}, fmt.Errorf("invalid Content-Length header: %v is out of [0, %v] range", length, maxContentLength)
+	}
+	content := make([]byte, length)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return message{}, err
+	}
+	var m message
+	if err := json.Unmarshal(content, &m); err != nil {
+		return message{}, &responseError{Code: parseErrorCode, Message: err.Error()}
+	}
+	return m, nil
+}
+
+func writeMessage(writer io.Writer, m message) error {
+	m.Jsonrpc = "2.0"
+	content, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(writer, "Content-Length: %v\r\n\r\n", len(content)); err != nil {
+		return err
+	}
+	_, err = writer.Write(content)
+	return err
+}
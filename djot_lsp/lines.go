@@ -0,0 +1,61 @@
+package djot_lsp
+
+import (
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// lineIndex converts byte offsets of the document to the LSP positions (line and UTF-16 code unit offset within the line) and back
+type lineIndex struct {
+	text   []byte
+	starts []int
+}
+
+func newLineIndex(text []byte) lineIndex {
+	starts := []int{0}
+	for i, c := range text {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return lineIndex{text: text, starts: starts}
+}
+
+func (l lineIndex) line(offset int) int {
+	return sort.Search(len(l.starts), func(i int) bool { return l.starts[i] > offset }) - 1
+}
+
+func (l lineIndex) position(offset int) position {
+	offset = min(max(offset, 0), len(l.text))
+	line := l.line(offset)
+	character := 0
+	for i := l.starts[line]; i < offset; {
+		r, size := utf8.DecodeRune(l.text[i:])
+		character += max(utf16.RuneLen(r), 1)
+		i += size
+	}
+	return position{Line: line, Character: character}
+}
+
+func (l lineIndex) offset(p position) int {
+	if p.Line < 0 {
+		return 0
+	}
+	if p.Line >= len(l.starts) {
+		return len(l.text)
+	}
+	i, character := l.starts[p.Line], 0
+	for i < len(l.text) && l.text[i] != '\n' && character < p.Character {
+		r, size := utf8.DecodeRune(l.text[i:])
+		character += max(utf16.RuneLen(r), 1)
+		i += size
+	}
+	return i
+}
+
+func (l lineIndex) lspRange(r tokenizer.Range) lspRange {
+	return lspRange{Start: l.position(r.Start), End: l.position(r.End)}
+}
@@ -0,0 +1,197 @@
+package djot_lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+// Server is the Language Server Protocol server for djot documents (see README for the list of supported features).
+// Server processes messages sequentially and holds only the documents opened by the client
+type Server struct {
+	parser    *djot_parser.Parser
+	documents map[string]*document
+	out       io.Writer
+}
+
+func NewServer(parser *djot_parser.Parser) *Server {
+	return &Server{parser: parser, documents: make(map[string]*document)}
+}
+
+// Serve processes messages from in and writes responses and notifications to out until exit notification or the end of input
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	reader := bufio.NewReader(in)
+	s.out = out
+	for {
+		m, err := readMessage(reader)
+		var rpcErr *responseError
+		if errors.As(err, &rpcErr) {
+			if err := writeMessage(out, message{Id: &nullId, Error: rpcErr}); err != nil {
+				return err
+			}
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if m.Method == "exit" {
+			return nil
+		}
+		if m.Id == nil {
+			s.notification(m.Method, m.Params)
+			continue
+		}
+		response := message{Id: m.Id}
+		result, err := s.request(m.Method, m.Params)
+		if errors.As(err, &rpcErr) {
+			response.Error = rpcErr
+		} else if err != nil {
+			response.Error = &responseError{Code: requestFailedCode, Message: err.Error()}
+		} else if response.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+		if err := writeMessage(out, response); err != nil {
+			return err
+		}
+	}
+}
+
+var nullId = json.RawMessage("null")
+
+func (s *Server) request(method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":           map[string]any{"openClose": true, "change": 1},
+				"documentSymbolProvider":     true,
+				"definitionProvider":         true,
+				"referencesProvider":         true,
+				"hoverProvider":              true,
+				"completionProvider":         map[string]any{"triggerCharacters": []string{"[", "^", "#"}},
+				"foldingRangeProvider":       true,
+				"documentFormattingProvider": true,
+				"semanticTokensProvider": map[string]any{
+					"legend": map[string]any{"tokenTypes": djot_parser.SemanticTokenTypes, "tokenModifiers": []string{}},
+					"full":   true,
+				},
+			},
+			"serverInfo": map[string]any{"name": "godjot"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/documentSymbol":
+		var p textDocumentParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.symbols(), nil
+	case "textDocument/definition":
+		var p textDocumentPositionParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.definition(p.TextDocument.Uri, d.lines.offset(p.Position)), nil
+	case "textDocument/references":
+		var p referenceParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.references(p.TextDocument.Uri, d.lines.offset(p.Position), p.Context.IncludeDeclaration), nil
+	case "textDocument/hover":
+		var p textDocumentPositionParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		if h, ok := d.hover(d.lines.offset(p.Position)); ok {
+			return h, nil
+		}
+		return nil, nil
+	case "textDocument/completion":
+		var p textDocumentPositionParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.completion(d.lines.offset(p.Position)), nil
+	case "textDocument/foldingRange":
+		var p textDocumentParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.foldingRanges(s.parser), nil
+	case "textDocument/formatting":
+		var p textDocumentParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return d.formatting(s.parser), nil
+	case "textDocument/semanticTokens/full":
+		var p textDocumentParams
+		d, err := s.document(params, &p, &p.TextDocument)
+		if err != nil {
+			return nil, err
+		}
+		return semanticTokens{Data: d.semanticTokens(s.parser)}, nil
+	}
+	return nil, &responseError{Code: methodNotFoundCode, Message: fmt.Sprintf("method %v is not supported", method)}
+}
+
+// document decodes request params and returns the document they refer to
+func (s *Server) document(params json.RawMessage, p any, identifier *textDocumentIdentifier) (*document, error) {
+	if err := json.Unmarshal(params, p); err != nil {
+		return nil, &responseError{Code: invalidParamsCode, Message: err.Error()}
+	}
+	d, ok := s.documents[identifier.Uri]
+	if !ok {
+		return nil, &responseError{Code: invalidParamsCode, Message: fmt.Sprintf("document %v is not opened", identifier.Uri)}
+	}
+	return d, nil
+}
+
+func (s *Server) notification(method string, params json.RawMessage) {
+	switch method {
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if json.Unmarshal(params, &p) == nil {
+			s.update(p.TextDocument.Uri, p.TextDocument.Version, []byte(p.TextDocument.Text))
+		}
+	case "textDocument/didChange":
+		var p didChangeParams
+		if json.Unmarshal(params, &p) == nil && len(p.ContentChanges) > 0 {
+			s.update(p.TextDocument.Uri, p.TextDocument.Version, []byte(p.ContentChanges[len(p.ContentChanges)-1].Text))
+		}
+	case "textDocument/didClose":
+		var p didCloseParams
+		if json.Unmarshal(params, &p) == nil {
+			delete(s.documents, p.TextDocument.Uri)
+			s.publish(publishDiagnosticsParams{Uri: p.TextDocument.Uri, Diagnostics: []diagnostic{}})
+		}
+	}
+}
+
+func (s *Server) update(uri string, version int, text []byte) {
+	d := newDocument(s.parser, text)
+	s.documents[uri] = d
+	s.publish(publishDiagnosticsParams{Uri: uri, Version: version, Diagnostics: d.diagnostics()})
+}
+
+func (s *Server) publish(params publishDiagnosticsParams) {
+	content, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	_ = writeMessage(s.out, message{Method: "textDocument/publishDiagnostics", Params: content})
+}
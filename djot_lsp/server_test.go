@@ -0,0 +1,146 @@
+package djot_lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+const testUri = "file:///test.djot"
+
+const testDocument = "# Intro\n\nSee [docs][] and [Intro][][^n].\n\n## Part\n\nMore [docs][] [p](#Part).   \n\n\n[docs]: https://example.com\n\n[^n]: Note text.\n"
+
+func request(id int, method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notification(method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func at(line, character int) map[string]any {
+	return map[string]any{"textDocument": map[string]any{"uri": testUri}, "position": map[string]any{"line": line, "character": character}}
+}
+
+// serve sends messages to the server and returns all its output messages (responses and notifications) as JSON
+func serve(t *testing.T, messages ...map[string]any) []string {
+	var in, out bytes.Buffer
+	for _, m := range messages {
+		content, err := json.Marshal(m)
+		require.Nil(t, err)
+		_, err = fmt.Fprintf(&in, "Content-Length: %v\r\n\r\n%s", len(content), content)
+		require.Nil(t, err)
+	}
+	require.Nil(t, NewServer(djot_parser.NewParser(djot_parser.Options{})).Serve(&in, &out))
+	var output []string
+	reader := bufio.NewReader(&out)
+	for {
+		m, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return output
+		}
+		require.Nil(t, err)
+		content, err := json.Marshal(m)
+		require.Nil(t, err)
+		output = append(output, string(content))
+	}
+}
+
+func TestServer(t *testing.T) {
+	open := notification("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": testUri, "version": 1, "text": testDocument}})
+	document := map[string]any{"textDocument": map[string]any{"uri": testUri}}
+	output := serve(t,
+		open,
+		request(1, "textDocument/definition", at(2, 5)),
+		request(2, "textDocument/definition", at(2, 18)),
+		request(3, "textDocument/definition", at(2, 27)),
+		request(4, "textDocument/references", map[string]any{"textDocument": document["textDocument"], "position": map[string]any{"line": 9, "character": 1}, "context": map[string]any{"includeDeclaration": true}}),
+		request(5, "textDocument/references", at(0, 2)),
+		request(6, "textDocument/hover", at(2, 5)),
+		request(7, "textDocument/hover", at(2, 27)),
+		request(8, "textDocument/documentSymbol", document),
+		request(9, "textDocument/completion", at(2, 11)),
+		request(10, "textDocument/completion", at(2, 28)),
+		request(11, "textDocument/completion", at(6, 19)),
+		request(12, "textDocument/foldingRange", document),
+		request(13, "textDocument/formatting", document),
+		request(14, "unknown/method", nil),
+		request(15, "shutdown", nil),
+		notification("exit", nil),
+	)
+	require.Equal(t, []string{
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///test.djot","version":1,"diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","id":1,"result":[{"uri":"file:///test.djot","range":{"start":{"line":9,"character":0},"end":{"line":10,"character":0}}}]}`,
+		`{"jsonrpc":"2.0","id":2,"result":[{"uri":"file:///test.djot","range":{"start":{"line":0,"character":0},"end":{"line":1,"character":0}}}]}`,
+		`{"jsonrpc":"2.0","id":3,"result":[{"uri":"file:///test.djot","range":{"start":{"line":11,"character":0},"end":{"line":12,"character":0}}}]}`,
+		`{"jsonrpc":"2.0","id":4,"result":[{"uri":"file:///test.djot","range":{"start":{"line":2,"character":4},"end":{"line":2,"character":12}}},{"uri":"file:///test.djot","range":{"start":{"line":6,"character":5},"end":{"line":6,"character":13}}},{"uri":"file:///test.djot","range":{"start":{"line":9,"character":0},"end":{"line":10,"character":0}}}]}`,
+		`{"jsonrpc":"2.0","id":5,"result":[{"uri":"file:///test.djot","range":{"start":{"line":2,"character":17},"end":{"line":2,"character":26}}}]}`,
+		`{"jsonrpc":"2.0","id":6,"result":{"contents":{"kind":"plaintext","value":"https://example.com"},"range":{"start":{"line":2,"character":4},"end":{"line":2,"character":12}}}}`,
+		`{"jsonrpc":"2.0","id":7,"result":{"contents":{"kind":"plaintext","value":"Note text."},"range":{"start":{"line":2,"character":26},"end":{"line":2,"character":30}}}}`,
+		`{"jsonrpc":"2.0","id":8,"result":[{"name":"Intro","detail":"#Intro","kind":15,"range":{"start":{"line":0,"character":0},"end":{"line":12,"character":0}},"selectionRange":{"start":{"line":0,"character":0},"end":{"line":1,"character":0}},"children":[{"name":"Part","detail":"#Part","kind":15,"range":{"start":{"line":4,"character":0},"end":{"line":12,"character":0}},"selectionRange":{"start":{"line":4,"character":0},"end":{"line":5,"character":0}}}]}]}`,
+		`{"jsonrpc":"2.0","id":9,"result":[{"label":"docs","kind":18,"detail":"https://example.com"},{"label":"Intro","kind":18,"detail":"#Intro"},{"label":"Part","kind":18,"detail":"#Part"}]}`,
+		`{"jsonrpc":"2.0","id":10,"result":[{"label":"n","kind":18,"detail":"footnote"}]}`,
+		`{"jsonrpc":"2.0","id":11,"result":[{"label":"Intro","kind":18,"detail":"Intro"},{"label":"Part","kind":18,"detail":"Part"}]}`,
+		`{"jsonrpc":"2.0","id":12,"result":[{"startLine":0,"endLine":11},{"startLine":4,"endLine":11}]}`,
+		`{"jsonrpc":"2.0","id":13,"result":[{"range":{"start":{"line":0,"character":0},"end":{"line":12,"character":0}},"newText":"# Intro\n\nSee [docs][] and [Intro][][^n].\n\n## Part\n\nMore [docs][] [p](#Part).\n\n[docs]: https://example.com\n\n[^n]: Note text.\n"}]}`,
+		`{"jsonrpc":"2.0","id":14,"error":{"code":-32601,"message":"method unknown/method is not supported"}}`,
+		`{"jsonrpc":"2.0","id":15,"result":null}`,
+	}, output)
+}
+
+func TestServerDiagnostics(t *testing.T) {
+	uri := map[string]any{"uri": testUri, "version": 2}
+	output := serve(t,
+		notification("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": testUri, "version": 1, "text": "😀 [a][b]{#x}\n"}}),
+		notification("textDocument/didChange", map[string]any{"textDocument": uri, "contentChanges": []any{map[string]any{"text": "😀 [a][b]{#x} [c]{#x}\n"}}}),
+		notification("textDocument/didClose", map[string]any{"textDocument": uri}),
+		request(1, "textDocument/hover", at(0, 0)),
+	)
+	require.Equal(t, []string{
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///test.djot","version":1,"diagnostics":[{"range":{"start":{"line":0,"character":3},"end":{"line":0,"character":9}},"severity":2,"source":"godjot","message":"undefined reference [b]"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///test.djot","version":2,"diagnostics":[{"range":{"start":{"line":0,"character":17},"end":{"line":0,"character":21}},"severity":2,"source":"godjot","message":"duplicate id #x"},{"range":{"start":{"line":0,"character":3},"end":{"line":0,"character":9}},"severity":2,"source":"godjot","message":"undefined reference [b]"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///test.djot","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"document file:///test.djot is not opened"}}`,
+	}, output)
+}
+
+func TestLineIndex(t *testing.T) {
+	text := []byte("a😀b\nпривет\n")
+	lines := newLineIndex(text)
+	for offset, expected := range map[int]position{0: {0, 0}, 1: {0, 1}, 5: {0, 3}, 7: {1, 0}, 9: {1, 1}, 20: {2, 0}} {
+		require.Equal(t, expected, lines.position(offset))
+		require.Equal(t, offset, lines.offset(expected))
+	}
+	require.Equal(t, 6, lines.offset(position{Line: 0, Character: 100}))
+}
+
+func TestFormat(t *testing.T) {
+	document := []byte("\n\n# Title  \n\n\n\ntext `a  \n b`  \n\n```\ncode  \n\n\n```\n\n\n")
+	require.Equal(t, "# Title\n\ntext `a  \n b`\n\n```\ncode  \n\n\n```\n", string(Format(document, djot_parser.NewParser(djot_parser.Options{}).Tokens(document))))
+}
+
+func TestServerSemanticTokens(t *testing.T) {
+	output := serve(t,
+		notification("textDocument/didOpen", map[string]any{"textDocument": map[string]any{"uri": testUri, "version": 1, "text": "😀 *é*\n\n# a\n"}}),
+		request(1, "textDocument/semanticTokens/full", map[string]any{"textDocument": map[string]any{"uri": testUri}}),
+	)
+	require.Equal(t, `{"jsonrpc":"2.0","id":1,"result":{"data":[0,3,1,0,0,0,1,1,3,0,0,1,1,0,0,2,0,1,0,0,0,2,1,1,0]}}`, output[1])
+}
+
+func TestServerInvalidContentLength(t *testing.T) {
+	for _, header := range []string{"Content-Length: -1", "Content-Length: 1073741824", "Content-Length: x"} {
+		t.Run(header, func(t *testing.T) {
+			in := bytes.NewBufferString(header + "\r\n\r\n{}")
+			err := NewServer(djot_parser.NewParser(djot_parser.Options{})).Serve(in, io.Discard)
+			require.ErrorContains(t, err, "invalid Content-Length header")
+		})
+	}
+}
@@ -10,12 +10,20 @@ type (
 	ConversionContext[T any] struct {
 		Format   string
 		Registry ConversionRegistry[T]
+		Metadata Metadata
+		// LinkResolver (optional) rewrites href of LinkNode and src of ImageNode before conversion function is called
+		LinkResolver LinkResolver
+		// Lines converts source ranges of the nodes to positions (see ConversionState.SourceSpan)
+		Lines SourceLines
 	}
 	ConversionState[T any] struct {
-		Format string
-		Writer T
-		Node   TreeNode[DjotNode]
-		Parent *TreeNode[DjotNode]
+		Format       string
+		Writer       T
+		Node         TreeNode[DjotNode]
+		Parent       *TreeNode[DjotNode]
+		Metadata     Metadata
+		LinkResolver LinkResolver
+		Lines        SourceLines
 	}
 	Conversion[T any]         func(state ConversionState[T], next func(Children))
 	ConversionRegistry[T any] map[DjotNode]Conversion[T]
@@ -30,6 +38,12 @@ func (context ConversionContext[T]) ConvertDjot(
 	return builder
 }
 
+// ConvertDjotDocument converts document nodes and makes document metadata and lines available to the conversion functions
+func (context ConversionContext[T]) ConvertDjotDocument(builder T, document DjotDocument) T {
+	context.Metadata, context.Lines = document.Metadata, document.Lines
+	return context.ConvertDjot(builder, document.Nodes...)
+}
+
 func (context ConversionContext[T]) convertDjot(
 	builder T,
 	parent *TreeNode[DjotNode],
@@ -41,11 +55,17 @@ func (context ConversionContext[T]) convertDjot(
 		if !ok {
 			continue
 		}
+		if context.LinkResolver != nil {
+			currentNode = context.LinkResolver.ResolveNode(currentNode)
+		}
 		state := ConversionState[T]{
-			Format: context.Format,
-			Writer: builder,
-			Node:   currentNode,
-			Parent: parent,
+			Format:       context.Format,
+			Writer:       builder,
+			Node:         currentNode,
+			Parent:       parent,
+			Metadata:     context.Metadata,
+			LinkResolver: context.LinkResolver,
+			Lines:        context.Lines,
 		}
 		conversion(state, func(c Children) {
 			if len(c) == 0 {
@@ -17,6 +17,7 @@ const (
 	HeadingLevelKey       = "$HeadingLevelKey"
 	SparseListNodeKey     = "$SparseListNodeKey"
 	DefinitionListItemKey = "$DefinitionListItemKey"
+	FootnoteNumberKey     = "$FootnoteNumberKey"
 
 	IdKey                  = "id"
 	RoleKey                = "role"
@@ -72,12 +73,27 @@ const (
 	LinkNode
 	ImageNode
 	SpanNode
+	// FootnoteReferenceNode wraps footnote reference link together with the footnote content (see Options.AttachFootnotes)
+	FootnoteReferenceNode
+	lastBuiltinNode = FootnoteReferenceNode
 )
 
 func (n DjotNode) IsList() bool {
 	return n == UnorderedListNode || n == OrderedListNode || n == TaskListNode || n == DefinitionListNode
 }
 
+// IsBlock returns true for the built-in block nodes; RawNode (which can be both inline and block) and user-defined nodes
+// (which kind is unknown) are not blocks
+func (n DjotNode) IsBlock() bool {
+	switch n {
+	case DocumentNode, SectionNode, ParagraphNode, HeadingNode, QuoteNode, UnorderedListNode, OrderedListNode, DefinitionListNode,
+		TaskListNode, ListItemNode, DefinitionTermNode, DefinitionItemNode, CodeNode, ThematicBreakNode, DivNode,
+		TableNode, TableCaptionNode, TableRowNode, TableHeaderNode, TableCellNode, ReferenceDefNode, FootnoteDefNode:
+		return true
+	}
+	return false
+}
+
 func (n DjotNode) String() string {
 	switch n {
 	case DocumentNode:
@@ -154,8 +170,13 @@ func (n DjotNode) String() string {
 		return "ImageNode"
 	case SpanNode:
 		return "SpanNode"
+	case FootnoteReferenceNode:
+		return "FootnoteReferenceNode"
 	default:
-		panic(fmt.Errorf("unexpected djot node: %d", n))
+		if name, ok := userNodeName(n); ok {
+			return name
+		}
+		return fmt.Sprintf("DjotNode(%d)", int(n))
 	}
 }
 
@@ -205,21 +226,96 @@ type DjotContext struct {
 	References          map[string][]byte
 	ReferenceAttributes map[string]tokenizer.Attributes
 	FootnoteId          map[string]int
+	Metadata            Metadata
+
+	// metadataStart is the start offset of the metadata block (-1 if document has no metadata)
+	metadataStart    int
+	inlineExtensions map[djot_tokenizer.DjotToken]InlineExtension
+	blockExtensions  map[djot_tokenizer.DjotToken]BlockExtension
+	// footnoteOrder holds referenced footnotes in the order of their first reference (footnote number is the index + 1)
+	footnoteOrder []string
+	// footnotes and footnoteBacklinks are filled during AST construction with footnotes content and references count
+	footnotes         map[string]footnoteDefinition
+	footnoteBacklinks map[string]int
+	attachFootnotes   bool
+	smartPunctuation  SmartPunctuation
+	// headingIds holds unique ids of the headings sections (keyed by the heading token start offset)
+	headingIds map[int]string
+	idPrefix   string
+	// budget is not nil only for BuildDjotDocumentContext
+	budget          *parseBudget
+	disableSections bool
+	disableEndnotes bool
+	sourcePositions bool
+	diagnostics     []Diagnostic
+	index           SourceIndex
 }
 
 func BuildDjotContext(document []byte, list tokenizer.TokenList[djot_tokenizer.DjotToken]) DjotContext {
+	return Options{}.BuildDjotContext(document, list)
+}
+
+func (o Options) BuildDjotContext(document []byte, list tokenizer.TokenList[djot_tokenizer.DjotToken]) DjotContext {
 	context := DjotContext{
 		References:          make(map[string][]byte),
 		ReferenceAttributes: make(map[string]tokenizer.Attributes),
 		FootnoteId:          make(map[string]int),
+		metadataStart:       -1,
+		headingIds:          make(map[int]string),
+		footnotes:           make(map[string]footnoteDefinition),
+		footnoteBacklinks:   make(map[string]int),
+		idPrefix:            o.IdPrefix,
+		attachFootnotes:     o.AttachFootnotes,
+		smartPunctuation:    o.SmartPunctuation,
+		disableSections:     o.DisableSections,
+		disableEndnotes:     o.DisableEndnotes,
+		sourcePositions:     o.SourcePositions,
+	}
+	slugger := o.Slugger
+	if slugger == nil {
+		slugger = CreateSectionId
+	}
+	usedIds, explicitIds := make(map[string]struct{}), make(map[string]struct{})
+	// explicit ids are reserved before generation of the heading ids, so generated id never duplicates explicit id of any element
+	for _, token := range list {
+		if id, ok := token.Attributes.TryGet(IdKey); ok && token.Type == djot_tokenizer.Attribute {
+			usedIds[id] = struct{}{}
+		}
+	}
+	if len(o.InlineExtensions) > 0 {
+		context.inlineExtensions = make(map[djot_tokenizer.DjotToken]InlineExtension, len(o.InlineExtensions))
+		for _, extension := range o.InlineExtensions {
+			context.inlineExtensions[extension.Type] = extension
+		}
+	}
+	if len(o.BlockExtensions) > 0 {
+		context.blockExtensions = make(map[djot_tokenizer.DjotToken]BlockExtension, len(o.BlockExtensions))
+		for _, extension := range o.BlockExtensions {
+			context.blockExtensions[extension.Type] = extension
+		}
 	}
 
-	footnoteId := 1
+	footnoteReferences := make([]string, 0)
+	footnoteReferenceRanges := make([]tokenizer.Range, 0)
+	definedReferences, definedFootnotes := make(map[string]struct{}), make(map[string]struct{})
+	type linkReference struct {
+		reference string
+		span      tokenizer.Range
+	}
+	linkReferences := make([]linkReference, 0)
 
+	firstBlock := true
 	i := 0
 	for i < len(list) {
 		var attributes tokenizer.Attributes
 		for i < len(list) && list[i].Type == djot_tokenizer.Attribute {
+			if id, ok := list[i].Attributes.TryGet(IdKey); ok {
+				if _, duplicate := explicitIds[id]; duplicate {
+					context.diagnose(WarningSeverity, list[i].Start, list[i].End, "duplicate id #%v", id)
+				}
+				explicitIds[id] = struct{}{}
+				context.index.symbol(&context.index.Ids, id, list[i].Start, list[i].End)
+			}
 			attributes.MergeWith(list[i].Attributes)
 			i++
 		}
@@ -230,25 +326,96 @@ func BuildDjotContext(document []byte, list tokenizer.TokenList[djot_tokenizer.D
 			continue
 		}
 		closeToken := list[i+openToken.JumpToPair]
+		isFirstBlock := firstBlock && openToken.Type != djot_tokenizer.DocumentBlock
+		if isFirstBlock {
+			firstBlock = false
+		}
 		switch openToken.Type {
+		case djot_tokenizer.MetadataBlock:
+			context.Metadata = context.parseMetadata(document[openToken.End:closeToken.Start], openToken, closeToken)
+			context.metadataStart = openToken.Start
+		case djot_tokenizer.CodeBlock:
+			if isFirstBlock && o.FrontMatter && openToken.Attributes.Get(djot_tokenizer.CodeLangKey) == "="+MetadataRawFormat {
+				// code block open token doesn't include info string, so content starts from the first inner token
+				context.Metadata = context.parseMetadata(document[list[i+1].Start:closeToken.Start], openToken, closeToken)
+				context.metadataStart = openToken.Start
+			}
 		case djot_tokenizer.ReferenceDefBlock:
 			reference := openToken.Attributes.Get(djot_tokenizer.ReferenceKey)
+			if _, ok := definedReferences[reference]; ok {
+				context.diagnose(WarningSeverity, openToken.Start, closeToken.End, "duplicate reference definition [%v]", reference)
+			}
+			definedReferences[reference] = struct{}{}
+			context.index.symbol(&context.index.LinkDefinitions, reference, openToken.Start, closeToken.End)
 			link := bytes.Trim(document[openToken.End:closeToken.Start], "\t\r\n ")
 			context.References[reference] = link
 			context.ReferenceAttributes[reference] = attributes
 		case djot_tokenizer.FootnoteDefBlock:
 			reference := openToken.Attributes.Get(djot_tokenizer.ReferenceKey)
-			context.FootnoteId[reference] = footnoteId
-			footnoteId++
+			if _, ok := definedFootnotes[reference]; ok {
+				context.diagnose(WarningSeverity, openToken.Start, closeToken.End, "duplicate footnote definition [^%v]", reference)
+			}
+			definedFootnotes[reference] = struct{}{}
+			context.index.symbol(&context.index.FootnoteDefinitions, reference, openToken.Start, closeToken.End)
+		case djot_tokenizer.FootnoteReferenceInline:
+			footnoteReferences = append(footnoteReferences, string(document[openToken.End:closeToken.Start]))
+			context.index.symbol(&context.index.FootnoteReferences, footnoteReferences[len(footnoteReferences)-1], openToken.Start, closeToken.End)
+			footnoteReferenceRanges = append(footnoteReferenceRanges, tokenizer.Range{Start: openToken.Start, End: closeToken.End})
+		case djot_tokenizer.LinkReferenceInline:
+			// link reference always follows the closed span: [text][reference] or ![text][reference]
+			spanClose := list[i-1]
+			if spanClose.JumpToPair >= 0 {
+				break
+			}
+			spanOpen := i - 1 + spanClose.JumpToPair
+			reference := normalizeLinkText(document[openToken.End:closeToken.Start])
+			if len(reference) == 0 {
+				reference = selectText(document, list[spanOpen+1:i-1])
+			}
+			linkReferences = append(linkReferences, linkReference{
+				reference: string(reference),
+				span:      tokenizer.Range{Start: list[spanOpen].Start, End: closeToken.End},
+			})
+			context.index.symbol(&context.index.LinkReferences, string(reference), list[spanOpen].Start, closeToken.End)
 		case djot_tokenizer.HeadingBlock:
-			headerId := CreateSectionId(string(selectText(document, list[i+1:i+openToken.JumpToPair])))
-			// don't overwrite reference if any
-			if _, ok := context.References[headerId]; !ok {
-				context.References[headerId] = []byte("#" + headerId)
+			headerText := strings.TrimSpace(string(selectText(document, list[i+1:i+openToken.JumpToPair])))
+			headerId := uniqueId(usedIds, slugger(headerText))
+			context.headingIds[openToken.Start] = headerId
+			context.index.Headings = append(context.index.Headings, HeadingSymbol{
+				Level: openToken.PrefixLength(document, '#'),
+				Id:    o.IdPrefix + headerId,
+				Title: headerText,
+				Range: tokenizer.Range{Start: openToken.Start, End: closeToken.End},
+			})
+			if o.DisableHeadingReferences {
+				break
+			}
+			// heading can be referenced by its text (and by its id for compatibility); don't overwrite reference if any
+			for _, reference := range []string{headerText, headerId} {
+				if _, ok := context.References[reference]; !ok {
+					context.References[reference] = []byte("#" + o.IdPrefix + headerId)
+				}
 			}
 		}
 		i++
 	}
+	o.References.mergeInto(&context)
+	// footnotes are numbered in the order of the first reference (including undefined ones)
+	for i, reference := range footnoteReferences {
+		if _, ok := context.FootnoteId[reference]; !ok {
+			context.footnoteOrder = append(context.footnoteOrder, reference)
+			context.FootnoteId[reference] = len(context.footnoteOrder)
+		}
+		_, defined := definedFootnotes[reference]
+		if _, shared := o.References.Footnotes[reference]; !defined && !shared {
+			context.diagnose(WarningSeverity, footnoteReferenceRanges[i].Start, footnoteReferenceRanges[i].End, "undefined footnote [^%v]", reference)
+		}
+	}
+	for _, link := range linkReferences {
+		if _, ok := context.References[link.reference]; !ok {
+			context.diagnose(WarningSeverity, link.span.Start, link.span.End, "undefined reference [%v]", link.reference)
+		}
+	}
 	return context
 }
 
@@ -319,6 +486,8 @@ func selectText(document []byte, list tokenizer.TokenList[djot_tokenizer.DjotTok
 	return text
 }
 
+// CreateSectionId is the default Slugger compatible with the djot.js implementation:
+// it keeps letters and digits as is and replaces every run of other symbols with single dash
 func CreateSectionId(s string) string {
 	id := strings.Builder{}
 	hasDash := false
@@ -397,10 +566,11 @@ func detectListProps(document []byte, token tokenizer.Token[djot_tokenizer.DjotT
 }
 
 func BuildDjotAst(document []byte) []TreeNode[DjotNode] {
-	tokens := djot_tokenizer.BuildDjotTokens(document)
-	context := BuildDjotContext(document, tokens)
-	ast := buildDjotAst(document, context, DjotLocalContext{}, tokens)
-	return ast
+	return defaultParser.Parse(document).Nodes
+}
+
+func BuildDjotDocument(document []byte) DjotDocument {
+	return defaultParser.Parse(document)
 }
 
 func isTight(list tokenizer.TokenList[djot_tokenizer.DjotToken]) bool {
@@ -444,11 +614,28 @@ func buildDjotAst(
 	if len(list) == 0 {
 		return nil
 	}
+	e := newTreeEmitter()
+	emitDjotAst(e, document, context, localContext, list)
+	return e.nodes()
+}
+
+// emitDjotAst passes nodes built from the token list to the emitter in the depth-first order
+func emitDjotAst(
+	e *astEmitter,
+	document []byte,
+	context DjotContext,
+	localContext DjotLocalContext,
+	list tokenizer.TokenList[djot_tokenizer.DjotToken],
+) {
+	if len(list) == 0 {
+		return
+	}
 
-	footnotes := make([]TreeNode[DjotNode], 0)
 	groupElementsPop := make(map[int]int)
 
 	groupElementsInsert := make(map[int]*TreeNode[DjotNode])
+	// tableCaptions holds position of the caption for the table (caption follows table rows but it's emitted before them)
+	tableCaptions := make(map[int]int)
 	assignedTableProps := make(map[int]TableProps)
 	{
 		groupElements := make([]*TreeNode[DjotNode], 0)
@@ -459,13 +646,8 @@ func buildDjotAst(
 			openToken := list[i]
 			switch openToken.Type {
 			case djot_tokenizer.PipeTableCaptionBlock:
-				groupElementsInsert[activeTableProps.TableIndex] = &TreeNode[DjotNode]{
-					Type: TableNode,
-					Children: []TreeNode[DjotNode]{{
-						Type:     TableCaptionNode,
-						Children: buildDjotAst(document, context, DjotLocalContext{TextNode: true}, list[i+1:i+openToken.JumpToPair]),
-					}},
-				}
+				groupElementsInsert[activeTableProps.TableIndex] = &TreeNode[DjotNode]{Type: TableNode}
+				tableCaptions[activeTableProps.TableIndex] = i
 			case djot_tokenizer.PipeTableBlock:
 				alignments := make([]string, 0)
 				columns := 0
@@ -505,6 +687,14 @@ func buildDjotAst(
 					assignedTableProps[i] = activeTableProps
 				}
 			case djot_tokenizer.HeadingBlock:
+				// without sections heading is the regular block which only closes active list
+				if context.disableSections {
+					if len(groupElements) > 0 && groupElements[len(groupElements)-1].Type.IsList() {
+						groupElementsPop[i] = 1
+						groupElements = groupElements[:len(groupElements)-1]
+					}
+					break
+				}
 				level := string(bytes.TrimSuffix(document[openToken.Start:openToken.End], []byte(" ")))
 				pop := 0
 				for {
@@ -519,9 +709,13 @@ func buildDjotAst(
 					pop++
 				}
 				groupElementsPop[i] = pop
+				headerId, ok := context.headingIds[openToken.Start]
+				if !ok {
+					headerId = CreateSectionId(string(selectText(document, list[i+1:i+openToken.JumpToPair])))
+				}
 				sectionNode := TreeNode[DjotNode]{Type: SectionNode, Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{
 					Key:   "id",
-					Value: CreateSectionId(string(selectText(document, list[i+1:i+openToken.JumpToPair]))),
+					Value: context.idPrefix + headerId,
 				})}
 				groupElementsInsert[i] = &sectionNode
 				groupElements = append(groupElements, &sectionNode)
@@ -568,15 +762,15 @@ func buildDjotAst(
 		}
 	}
 
-	nodes := make([]TreeNode[DjotNode], 0)
-	groups := []*[]TreeNode[DjotNode]{&nodes}
-	nodesRef := &nodes
+	openGroups := 0
 	isSparseList, insertedNodeType := false, DjotNode(0)
 	tableCellId := 0
+	blocks := e.blocks.forList(list)
 	{
 		i := 0
-		for i < len(list) {
+		for i < len(list) && !e.stopped && !context.budget.exhausted() {
 			var attributes tokenizer.Attributes
+			blockStart := i
 			if !localContext.TextNode {
 				aggregateAttributes(&i, &attributes, list)
 			}
@@ -591,18 +785,31 @@ func buildDjotAst(
 			if localContext.TextNode {
 				aggregateAttributes(&nextI, &attributes, list)
 			}
-			if groupElementsPop[i] > 0 {
-				groups = groups[:len(groups)-groupElementsPop[i]]
-				nodesRef = groups[len(groups)-1]
+			for pop := 0; pop < groupElementsPop[i]; pop++ {
+				e.leave()
+				openGroups--
 			}
 			if insert, ok := groupElementsInsert[i]; ok {
 				_, isSparseList = insert.Attributes.TryGet(SparseListNodeKey)
 				insertedNodeType = insert.Type
 
-				*nodesRef = append(*nodesRef, *insert)
-				nodesRef = &(*nodesRef)[len(*nodesRef)-1].Children
-				groups = append(groups, nodesRef)
+				e.enter(insert.Type, insert.Attributes)
+				if caption, ok := tableCaptions[i]; ok {
+					e.enter(TableCaptionNode, tokenizer.Attributes{})
+					emitDjotAst(e, document, context, DjotLocalContext{TextNode: true}, list[caption+1:caption+list[caption].JumpToPair])
+					e.leave()
+				}
+				openGroups++
+			}
+			blockKey, cacheable, reusable := "", false, false
+			if blocks != nil {
+				blockKey, cacheable, reusable = blocks.key(document, context, list, blockStart, i, insertedNodeType, isSparseList)
+				if reusable && blocks.reuse(e, blockKey) {
+					i = nextI
+					continue
+				}
 			}
+			blockDepth, blockChildren := len(e.stack)-1, len(e.stack[len(e.stack)-1].Children)
 
 			switch openToken.Type {
 			case
@@ -617,77 +824,66 @@ func buildDjotAst(
 				djot_tokenizer.SuperscriptInline,
 				djot_tokenizer.InsertInline,
 				djot_tokenizer.DeleteInline:
-				*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-					Type: convertTokenToNode(openToken.Type),
-					Children: buildDjotAst(
-						document,
-						context,
-						DjotLocalContext{
-							TextNode: localContext.TextNode ||
-								openToken.Type == djot_tokenizer.ParagraphBlock ||
-								openToken.Type == djot_tokenizer.HeadingBlock,
-						},
-						trimPadding(document, list[i+1:i+openToken.JumpToPair]),
-					),
-					Attributes: attributes,
-				})
-			case djot_tokenizer.CodeBlock:
-				lang := openToken.Attributes.Get(djot_tokenizer.CodeLangKey)
-				internal := buildDjotAst(
+				e.enter(convertTokenToNode(openToken.Type), attributes)
+				emitDjotAst(
+					e,
 					document,
 					context,
-					DjotLocalContext{TextNode: true},
+					DjotLocalContext{
+						TextNode: localContext.TextNode ||
+							openToken.Type == djot_tokenizer.ParagraphBlock ||
+							openToken.Type == djot_tokenizer.HeadingBlock,
+					},
 					trimPadding(document, list[i+1:i+openToken.JumpToPair]),
 				)
+				e.leave()
+			case djot_tokenizer.CodeBlock:
+				// raw metadata block is analyzed in the BuildDjotContext function
+				if openToken.Start == context.metadataStart {
+					break
+				}
+				lang := openToken.Attributes.Get(djot_tokenizer.CodeLangKey)
 				if suffix, ok := strings.CutPrefix(lang, "="); ok {
 					attributes.Set(RawBlockFormatKey, suffix)
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       RawNode,
-						Children:   internal,
-						Attributes: attributes,
-					})
+					e.enter(RawNode, attributes)
 				} else {
 					if lang != "" {
 						attributes.Append(djot_tokenizer.DjotAttributeClassKey, "language-"+lang)
 					}
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       CodeNode,
-						Children:   internal,
-						Attributes: attributes,
-					})
+					e.enter(CodeNode, attributes)
 				}
+				emitDjotAst(e, document, context, DjotLocalContext{TextNode: true}, trimPadding(document, list[i+1:i+openToken.JumpToPair]))
+				e.leave()
 			case djot_tokenizer.ThematicBreakToken:
-				*nodesRef = append(*nodesRef, TreeNode[DjotNode]{Type: ThematicBreakNode, Attributes: attributes})
+				e.enter(ThematicBreakNode, attributes)
+				e.leave()
 			case djot_tokenizer.HeadingBlock:
 				if openToken.Type == djot_tokenizer.HeadingBlock {
 					attributes.Set(
 						HeadingLevelKey, string(bytes.TrimSuffix(document[openToken.Start:openToken.End], []byte(" "))),
 					)
 				}
-				*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-					Type: convertTokenToNode(openToken.Type),
-					Children: buildDjotAst(
-						document,
-						context,
-						DjotLocalContext{
-							TextNode: localContext.TextNode ||
-								openToken.Type == djot_tokenizer.ParagraphBlock ||
-								openToken.Type == djot_tokenizer.HeadingBlock ||
-								openToken.Type == djot_tokenizer.CodeBlock,
-						},
-						trimPadding(document, list[i+1:i+openToken.JumpToPair]),
-					),
-					Attributes: attributes,
-				})
+				if _, ok := attributes.TryGet(IdKey); !ok && context.disableSections && context.headingIds[openToken.Start] != "" {
+					attributes.Set(IdKey, context.idPrefix+context.headingIds[openToken.Start])
+				}
+				e.enter(convertTokenToNode(openToken.Type), attributes)
+				emitDjotAst(
+					e,
+					document,
+					context,
+					DjotLocalContext{
+						TextNode: localContext.TextNode ||
+							openToken.Type == djot_tokenizer.ParagraphBlock ||
+							openToken.Type == djot_tokenizer.HeadingBlock ||
+							openToken.Type == djot_tokenizer.CodeBlock,
+					},
+					trimPadding(document, list[i+1:i+openToken.JumpToPair]),
+				)
+				e.leave()
 			case djot_tokenizer.SymbolsInline:
-				*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-					Type: SymbolsNode,
-					Children: []TreeNode[DjotNode]{{
-						Type: TextNode,
-						Text: document[openToken.End:closeToken.Start],
-					}},
-					Attributes: attributes,
-				})
+				e.enter(SymbolsNode, attributes)
+				e.text(document[openToken.End:closeToken.Start])
+				e.leave()
 			case djot_tokenizer.AutolinkInline:
 				link := normalizeLinkText(document[openToken.End:closeToken.Start])
 				href := string(link)
@@ -695,11 +891,9 @@ func buildDjotAst(
 					href = "mailto:" + href
 				}
 				attributes.Set(LinkHrefKey, href)
-				*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-					Type:       LinkNode,
-					Children:   []TreeNode[DjotNode]{{Type: TextNode, Text: link}},
-					Attributes: attributes,
-				})
+				e.enter(LinkNode, attributes)
+				e.text(link)
+				e.leave()
 			case djot_tokenizer.VerbatimInline:
 				text := document[openToken.End:list[i+openToken.JumpToPair].Start]
 				if trimmed := bytes.Trim(text, " "); bytes.HasPrefix(trimmed, []byte("`")) && bytes.HasSuffix(trimmed, []byte("`")) {
@@ -711,24 +905,33 @@ func buildDjotAst(
 					attributes.Set(RawInlineFormatKey, string(document[rawFormatOpen.End:rawFormatClose.Start]))
 					nextI += rawFormatOpen.JumpToPair + 1
 				}
-				*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-					Type: VerbatimNode,
-					Children: []TreeNode[DjotNode]{{
-						Type: TextNode,
-						Text: text,
-					}},
-					Attributes: attributes,
-				})
+				e.enter(VerbatimNode, attributes)
+				e.text(text)
+				e.leave()
 			case djot_tokenizer.FootnoteReferenceInline:
-				footnoteId := context.FootnoteId[string(document[openToken.End:closeToken.Start])]
-				attributes.Set(IdKey, fmt.Sprintf("fnref%v", footnoteId))
-				attributes.Set(LinkHrefKey, fmt.Sprintf("#fn%v", footnoteId))
+				reference := string(document[openToken.End:closeToken.Start])
+				footnoteId := context.FootnoteId[reference]
+				context.footnoteBacklinks[reference]++
+				attributes.Set(IdKey, footnoteReferenceId(context, footnoteId, context.footnoteBacklinks[reference]))
+				attributes.Set(LinkHrefKey, fmt.Sprintf("#%vfn%v", context.idPrefix, footnoteId))
 				attributes.Set(RoleKey, "doc-noteref")
-				*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
+				linkNode := TreeNode[DjotNode]{
 					Type:       LinkNode,
 					Children:   []TreeNode[DjotNode]{{Type: SuperscriptNode, Children: []TreeNode[DjotNode]{{Type: TextNode, Text: []byte(fmt.Sprintf("%v", footnoteId))}}}},
 					Attributes: attributes,
-				})
+				}
+				if context.attachFootnotes {
+					// footnote content is attached after AST construction because footnote can be defined after the reference
+					linkNode = TreeNode[DjotNode]{
+						Type:     FootnoteReferenceNode,
+						Children: []TreeNode[DjotNode]{linkNode, {Type: FootnoteDefNode}},
+						Attributes: tokenizer.NewAttributes(
+							tokenizer.AttributeEntry{Key: djot_tokenizer.ReferenceKey, Value: reference},
+							tokenizer.AttributeEntry{Key: FootnoteNumberKey, Value: strconv.Itoa(footnoteId)},
+						),
+					}
+				}
+				e.subtree(linkNode)
 			case djot_tokenizer.ImageSpanInline:
 				var nextToken tokenizer.Token[djot_tokenizer.DjotToken]
 				if nextI < len(list) {
@@ -749,10 +952,8 @@ func buildDjotAst(
 				if nextToken.Type == djot_tokenizer.LinkUrlInline {
 					attributes.Set(ImgAltKey, string(selectText(document, list[i+1:i+openToken.JumpToPair])))
 					attributes.Set(ImgSrcKey, string(normalizeLinkText(document[nextToken.End:list[nextI+nextToken.JumpToPair].Start])))
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       ImageNode,
-						Attributes: attributes,
-					})
+					e.enter(ImageNode, attributes)
+					e.leave()
 					nextI += nextToken.JumpToPair + 1
 				} else if nextToken.Type == djot_tokenizer.LinkReferenceInline {
 					reference := normalizeLinkText(document[nextToken.End:list[nextI+nextToken.JumpToPair].Start])
@@ -764,21 +965,13 @@ func buildDjotAst(
 						attributes.Set(ImgSrcKey, href)
 						attributes.MergeWith(context.ReferenceAttributes[string(reference)])
 					}
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       ImageNode,
-						Attributes: attributes,
-					})
+					e.enter(ImageNode, attributes)
+					e.leave()
 					nextI += nextToken.JumpToPair + 1
 				} else {
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type: TextNode,
-						Text: textBytes,
-					})
-					*nodesRef = append(*nodesRef, buildDjotAst(document, context, localContext, list[i+1:i+openToken.JumpToPair])...)
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type: TextNode,
-						Text: document[closeToken.Start:closeToken.End],
-					})
+					e.text(textBytes)
+					emitDjotAst(e, document, context, localContext, list[i+1:i+openToken.JumpToPair])
+					e.text(document[closeToken.Start:closeToken.End])
 				}
 				nextI += attributesAfter
 			case djot_tokenizer.SpanInline:
@@ -790,11 +983,9 @@ func buildDjotAst(
 					attributes.Set(LinkHrefKey, string(normalizeLinkText(document[nextToken.End:list[nextI+nextToken.JumpToPair].Start])))
 					nextI += nextToken.JumpToPair + 1
 					aggregateAttributes(&nextI, &attributes, list)
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       LinkNode,
-						Children:   buildDjotAst(document, context, localContext, list[i+1:i+openToken.JumpToPair]),
-						Attributes: attributes,
-					})
+					e.enter(LinkNode, attributes)
+					emitDjotAst(e, document, context, localContext, list[i+1:i+openToken.JumpToPair])
+					e.leave()
 				} else if nextToken.Type == djot_tokenizer.LinkReferenceInline {
 					reference := normalizeLinkText(document[nextToken.End:list[nextI+nextToken.JumpToPair].Start])
 					if len(reference) == 0 {
@@ -806,136 +997,73 @@ func buildDjotAst(
 					}
 					nextI += nextToken.JumpToPair + 1
 					aggregateAttributes(&nextI, &attributes, list)
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       LinkNode,
-						Attributes: attributes,
-						Children:   buildDjotAst(document, context, localContext, list[i+1:i+openToken.JumpToPair]),
-					})
+					e.enter(LinkNode, attributes)
+					emitDjotAst(e, document, context, localContext, list[i+1:i+openToken.JumpToPair])
+					e.leave()
 				} else if attributes.Size() > 0 {
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       SpanNode,
-						Children:   buildDjotAst(document, context, localContext, list[i+1:i+openToken.JumpToPair]),
-						Attributes: attributes,
-					})
+					e.enter(SpanNode, attributes)
+					emitDjotAst(e, document, context, localContext, list[i+1:i+openToken.JumpToPair])
+					e.leave()
 				} else {
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type: TextNode,
-						Text: textBytes,
-					})
-					*nodesRef = append(*nodesRef, buildDjotAst(document, context, localContext, list[i+1:i+openToken.JumpToPair])...)
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type: TextNode,
-						Text: document[closeToken.Start:closeToken.End],
-					})
+					e.text(textBytes)
+					emitDjotAst(e, document, context, localContext, list[i+1:i+openToken.JumpToPair])
+					e.text(document[closeToken.Start:closeToken.End])
 				}
 			case djot_tokenizer.EscapedSymbolInline:
 				if localContext.TextNode {
 					text := textBytes
 					if text[len(text)-1] == '\n' {
-						*nodesRef = append(*nodesRef, TreeNode[DjotNode]{Type: LineBreakNode})
+						e.enter(LineBreakNode, tokenizer.Attributes{})
+						e.leave()
 					} else {
-						*nodesRef = append(*nodesRef, TreeNode[DjotNode]{Type: TextNode, Text: text[1:]})
+						e.text(text[1:])
 					}
 				}
 			case djot_tokenizer.SmartSymbolInline:
 				textString := strings.Trim(string(textBytes), "{}")
 				if localContext.TextNode {
-					quoteDirection := detectQuoteDirection(document, openToken.Start)
-					if openToken.Type == djot_tokenizer.SmartSymbolInline {
-						if textString == "\"" && quoteDirection == OpenQuote {
-							textBytes = []byte(`“`)
-						} else if textString == "\"" && quoteDirection == CloseQuote {
-							textBytes = []byte(`”`)
-						} else if textString == "'" && quoteDirection == OpenQuote {
-							textBytes = []byte(`‘`)
-						} else if textString == "'" && quoteDirection == CloseQuote {
-							textBytes = []byte(`’`)
-						} else if textString == "..." {
-							textBytes = []byte(`…`)
-						} else if strings.Count(textString, "-") == len(textString) {
-							if len(textString)%3 == 0 {
-								textBytes = bytes.Repeat([]byte(`—`), len(textString)/3)
-							} else if len(textString)%2 == 0 {
-								textBytes = bytes.Repeat([]byte(`–`), len(textString)/2)
-							} else {
-								textBytes = append(bytes.Repeat([]byte(`–`), (len(textString)-3)/2), []byte(`—`)...)
-							}
-						}
-					}
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{Type: TextNode, Text: textBytes})
+					e.text(context.smartPunctuation.convert(document, openToken, textString, textBytes))
 				}
 			case djot_tokenizer.ListItemBlock:
 				if insertedNodeType == DefinitionListNode {
 					attributes.Set(DefinitionListItemKey, "true")
-					var definitionTermChildren []TreeNode[DjotNode]
+					e.enter(DefinitionTermNode, attributes)
 					if list[i+1].Type == djot_tokenizer.ParagraphBlock {
-						definitionTermChildren = buildDjotAst(document, context, DjotLocalContext{TextNode: true}, trimPadding(document, list[i+2:i+1+list[i+1].JumpToPair]))
+						emitDjotAst(e, document, context, DjotLocalContext{TextNode: true}, trimPadding(document, list[i+2:i+1+list[i+1].JumpToPair]))
 					}
-					definitionItemChildren := buildDjotAst(document, context, DjotLocalContext{}, list[i+1+list[i+1].JumpToPair+1:i+openToken.JumpToPair])
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       DefinitionTermNode,
-						Children:   definitionTermChildren,
-						Attributes: attributes,
-					})
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:     DefinitionItemNode,
-						Children: definitionItemChildren,
-					})
+					e.leave()
+					e.enter(DefinitionItemNode, tokenizer.Attributes{})
+					emitDjotAst(e, document, context, DjotLocalContext{}, list[i+1+list[i+1].JumpToPair+1:i+openToken.JumpToPair])
+					e.leave()
 				} else {
 					if insertedNodeType == TaskListNode && bytes.HasPrefix(openToken.Bytes(document), []byte("- [ ]")) {
 						attributes.Append(djot_tokenizer.DjotAttributeClassKey, UncheckedTaskItemClass)
 					} else if insertedNodeType == TaskListNode {
 						attributes.Append(djot_tokenizer.DjotAttributeClassKey, CheckedTaskItemClass)
 					}
+					e.enter(ListItemNode, attributes)
 					if !isSparseList && list[i+1].Type == djot_tokenizer.ParagraphBlock {
-						children := buildDjotAst(document, context, DjotLocalContext{TextNode: true}, list[i+2:i+1+list[i+1].JumpToPair])
+						emitDjotAst(e, document, context, DjotLocalContext{TextNode: true}, list[i+2:i+1+list[i+1].JumpToPair])
 						if list[i+1+list[i+1].JumpToPair].End == len(document) {
-							children = append(children, TreeNode[DjotNode]{Type: TextNode, Text: []byte("\n")})
+							e.text([]byte("\n"))
 						}
-						children = append(children, buildDjotAst(document, context, DjotLocalContext{TextNode: false}, list[i+1+list[i+1].JumpToPair+1:i+openToken.JumpToPair])...)
-						*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-							Type:       ListItemNode,
-							Children:   children,
-							Attributes: attributes,
-						})
+						emitDjotAst(e, document, context, DjotLocalContext{TextNode: false}, list[i+1+list[i+1].JumpToPair+1:i+openToken.JumpToPair])
 					} else {
-						*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-							Type:       ListItemNode,
-							Children:   buildDjotAst(document, context, localContext, list[i+1:i+openToken.JumpToPair]),
-							Attributes: attributes,
-						})
+						emitDjotAst(e, document, context, localContext, list[i+1:i+openToken.JumpToPair])
 					}
+					e.leave()
 				}
 			case djot_tokenizer.FootnoteDefBlock:
-				footnoteId := context.FootnoteId[attributes.Get(djot_tokenizer.ReferenceKey)]
-				children := buildDjotAst(document, context, DjotLocalContext{}, list[i+1:i+openToken.JumpToPair])
-				attributes.Set(LinkHrefKey, fmt.Sprintf("#fnref%v", footnoteId))
-				attributes.Set("role", "doc-backlink")
-				backrefLinkNode := TreeNode[DjotNode]{
-					Type:       LinkNode,
-					Children:   []TreeNode[DjotNode]{{Type: TextNode, Text: []byte("↩︎︎")}},
+				// footnotes content is rendered at the end of the document (see appendEndnotes)
+				context.footnotes[attributes.Get(djot_tokenizer.ReferenceKey)] = footnoteDefinition{
 					Attributes: attributes,
+					Children:   buildDjotAst(document, context, DjotLocalContext{}, list[i+1:i+openToken.JumpToPair]),
 				}
-				if len(children) > 0 && children[len(children)-1].Type == ParagraphNode {
-					children[len(children)-1].Children = append(children[len(children)-1].Children, backrefLinkNode)
-				} else {
-					children = append(children, TreeNode[DjotNode]{Type: ParagraphNode, Children: []TreeNode[DjotNode]{backrefLinkNode}})
-				}
-				footnotes = append(footnotes, TreeNode[DjotNode]{
-					Type: ListItemNode,
-					Children: []TreeNode[DjotNode]{{
-						Type:       FootnoteDefNode,
-						Children:   children,
-						Attributes: attributes,
-					}},
-					Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: "id", Value: fmt.Sprintf("fn%v", footnoteId)}),
-				})
 			case djot_tokenizer.PipeTableBlock:
 				if !assignedTableProps[i].Ignore {
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:     TableRowNode,
-						Children: buildDjotAst(document, context, DjotLocalContext{TextNode: true, TableNode: true, TableProps: assignedTableProps[i]}, trimPadding(document, list[i+1:i+openToken.JumpToPair])),
-					})
+					e.enter(TableRowNode, tokenizer.Attributes{})
+					emitDjotAst(e, document, context, DjotLocalContext{TextNode: true, TableNode: true, TableProps: assignedTableProps[i]}, trimPadding(document, list[i+1:i+openToken.JumpToPair]))
+					e.leave()
 				}
 			case djot_tokenizer.PipeTableSeparator:
 				if localContext.TableNode {
@@ -951,27 +1079,23 @@ func buildDjotAst(
 					if alignment != DefaultAlignment {
 						attributes.Set("style", fmt.Sprintf("text-align: %v;", alignment))
 					}
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-						Type:       nodeType,
-						Children:   buildDjotAst(document, context, DjotLocalContext{TextNode: true}, trimPadding(document, list[i+1:i+openToken.JumpToPair])),
-						Attributes: attributes,
-					})
+					e.enter(nodeType, attributes)
+					emitDjotAst(e, document, context, DjotLocalContext{TextNode: true}, trimPadding(document, list[i+1:i+openToken.JumpToPair]))
+					e.leave()
 				} else {
-					*nodesRef = append(*nodesRef, TreeNode[DjotNode]{Type: TextNode, Text: textBytes})
-					*nodesRef = append(*nodesRef, buildDjotAst(document, context, localContext, trimPadding(document, list[i+1:i+openToken.JumpToPair]))...)
+					e.text(textBytes)
+					emitDjotAst(e, document, context, localContext, trimPadding(document, list[i+1:i+openToken.JumpToPair]))
 				}
 			case djot_tokenizer.None:
 				if localContext.TextNode {
 					if attributes.Size() > 0 {
 						split := bytes.LastIndexByte(textBytes, ' ')
-						*nodesRef = append(*nodesRef, TreeNode[DjotNode]{Type: TextNode, Text: textBytes[:split+1]})
-						*nodesRef = append(*nodesRef, TreeNode[DjotNode]{
-							Type:       SpanNode,
-							Attributes: attributes,
-							Children:   []TreeNode[DjotNode]{{Type: TextNode, Text: textBytes[split+1:]}},
-						})
+						e.text(textBytes[:split+1])
+						e.enter(SpanNode, attributes)
+						e.text(textBytes[split+1:])
+						e.leave()
 					} else {
-						*nodesRef = append(*nodesRef, TreeNode[DjotNode]{Type: TextNode, Text: textBytes})
+						e.text(textBytes)
 					}
 				}
 			// attributes processed separately in the aggregateAttributes before main switchQ
@@ -979,26 +1103,33 @@ func buildDjotAst(
 			// these types need some context before them and they analyzed inside relevant branches in the main switch
 			case djot_tokenizer.RawFormatInline, djot_tokenizer.LinkUrlInline, djot_tokenizer.LinkReferenceInline:
 			// this types analyzed in the BuildDjotContext function
-			case djot_tokenizer.ReferenceDefBlock:
+			case djot_tokenizer.ReferenceDefBlock, djot_tokenizer.MetadataBlock:
 			// this types analyzed in the previous switch
 			case djot_tokenizer.PipeTableCaptionBlock:
 			// these types are intentionally skipped
 			case djot_tokenizer.Padding, djot_tokenizer.Ignore:
 			default:
-				panic(fmt.Errorf("unexpected tokenizer type: %v", openToken.Type))
+				if extension, ok := context.blockExtensions[openToken.Type]; ok {
+					emitBlockExtension(e, document, context, extension, attributes, list[i:nextI])
+				} else if extension, ok := context.inlineExtensions[openToken.Type]; ok {
+					if localContext.TextNode {
+						emitInlineExtension(e, document, context, extension, attributes, list[i:nextI])
+					}
+				} else {
+					panic(fmt.Errorf("unexpected tokenizer type: %v", openToken.Type))
+				}
+			}
+			if context.sourcePositions && isSourceBlock(context, openToken.Type) {
+				e.setSource(blockDepth, blockChildren, sourceRange(document, list, i))
+			}
+			if cacheable {
+				children := e.stack[blockDepth].Children
+				blocks.store(blockKey, children[blockChildren:len(children):len(children)])
 			}
 			i = nextI
 		}
 	}
-	if len(footnotes) > 0 {
-		nodes = append(nodes, TreeNode[DjotNode]{
-			Type:       SectionNode,
-			Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: "role", Value: "doc-endnotes"}),
-			Children: []TreeNode[DjotNode]{
-				{Type: ThematicBreakNode},
-				{Type: OrderedListNode, Children: footnotes},
-			},
-		})
-	}
-	return nodes
+	for ; openGroups > 0; openGroups-- {
+		e.leave()
+	}
 }
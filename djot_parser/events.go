@@ -0,0 +1,179 @@
+package djot_parser
+
+import (
+	"fmt"
+	"iter"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+type EventKind int
+
+const (
+	EnterEvent EventKind = iota + 1
+	LeaveEvent
+	TextEvent
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EnterEvent:
+		return "enter"
+	case LeaveEvent:
+		return "leave"
+	case TextEvent:
+		return "text"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event is the single step of the depth-first AST traversal:
+//   - Enter and Leave events surround children of the node (Attributes are set for both of them)
+//   - Text event corresponds to the TextNode (Type is TextNode and Text holds node text)
+//
+// Events hold exactly the same data as AST built by Parser.Parse (references are resolved and attributes are merged)
+type Event struct {
+	Kind       EventKind
+	Type       DjotNode
+	Attributes tokenizer.Attributes
+	Text       []byte
+}
+
+// astEmitter receives nodes from emitDjotAst in the depth-first order: it either builds the tree (if handler is nil)
+// or passes events to the handler without tree allocation
+type astEmitter struct {
+	handler func(event Event) bool
+	// stack holds currently open nodes (with root pseudo-node at the bottom); children are collected only for the tree
+	stack   []TreeNode[DjotNode]
+	stopped bool
+	// blocks (optional) holds subtrees of the top-level blocks which can be reused (see IncrementalDocument)
+	blocks *blockCache
+	// budget (optional) accounts emitted nodes (see Parser.ParseContext)
+	budget *parseBudget
+}
+
+func newTreeEmitter() *astEmitter {
+	return &astEmitter{stack: make([]TreeNode[DjotNode], 1, 16)}
+}
+
+func newEventEmitter(handler func(event Event) bool) *astEmitter {
+	return &astEmitter{handler: handler, stack: make([]TreeNode[DjotNode], 1, 16)}
+}
+
+// nodes returns children of the root pseudo-node (built tree)
+func (e *astEmitter) nodes() []TreeNode[DjotNode] { return e.stack[0].Children }
+
+func (e *astEmitter) emit(event Event) {
+	if !e.stopped && !e.handler(event) {
+		e.stopped = true
+	}
+}
+
+func (e *astEmitter) enter(nodeType DjotNode, attributes tokenizer.Attributes) {
+	e.budget.addNode()
+	e.stack = append(e.stack, TreeNode[DjotNode]{Type: nodeType, Attributes: attributes})
+	if e.handler != nil {
+		e.emit(Event{Kind: EnterEvent, Type: nodeType, Attributes: attributes})
+	}
+}
+
+func (e *astEmitter) leave() {
+	node := e.stack[len(e.stack)-1]
+	e.stack = e.stack[:len(e.stack)-1]
+	if e.handler != nil {
+		e.emit(Event{Kind: LeaveEvent, Type: node.Type, Attributes: node.Attributes})
+		return
+	}
+	parent := &e.stack[len(e.stack)-1]
+	parent.Children = append(parent.Children, node)
+}
+
+func (e *astEmitter) text(text []byte) {
+	e.budget.addNode()
+	if e.handler != nil {
+		e.emit(Event{Kind: TextEvent, Type: TextNode, Text: text})
+		return
+	}
+	parent := &e.stack[len(e.stack)-1]
+	parent.Children = append(parent.Children, TreeNode[DjotNode]{Type: TextNode, Text: text})
+}
+
+// setSource assigns source range to the nodes added to the open node at depth starting from the child with index from
+func (e *astEmitter) setSource(depth, from int, source tokenizer.Range) {
+	if e.handler != nil {
+		return
+	}
+	children := e.stack[depth].Children
+	for i := from; i < len(children); i++ {
+		children[i].Source = source
+	}
+}
+
+// replay builds the tree from the event emitted by another astEmitter
+func (e *astEmitter) replay(event Event) {
+	switch event.Kind {
+	case EnterEvent:
+		e.enter(event.Type, event.Attributes)
+	case LeaveEvent:
+		e.leave()
+	case TextEvent:
+		e.text(event.Text)
+	}
+}
+
+// subtree emits already built node
+func (e *astEmitter) subtree(node TreeNode[DjotNode]) {
+	if e.handler == nil {
+		e.budget.addTrees([]TreeNode[DjotNode]{node})
+		parent := &e.stack[len(e.stack)-1]
+		parent.Children = append(parent.Children, node)
+		return
+	}
+	if node.Type == TextNode {
+		e.text(node.Text)
+		return
+	}
+	e.enter(node.Type, node.Attributes)
+	for _, child := range node.Children {
+		e.subtree(child)
+	}
+	e.leave()
+}
+
+// ParseEvents passes document AST to the handler as the stream of events without building the tree;
+// parsing stops as soon as handler returns false. Returned document holds everything except Nodes.
+//
+// Footnotes content is the only part of the document which is materialized (endnotes section is emitted at the end of the document),
+// so content of the FootnoteDefNode attached to the FootnoteReferenceNode (see Options.AttachFootnotes) is always empty
+func (p *Parser) ParseEvents(document []byte, handler func(event Event) bool) DjotDocument {
+	tokens := p.tokenizerOptions.BuildDjotTokens(document)
+	context := p.options.BuildDjotContext(document, tokens)
+	e := newEventEmitter(handler)
+	// document node is emitted explicitly to put endnotes section at the end of it (see buildDocumentNodes)
+	e.enter(DocumentNode, tokens[0].Attributes)
+	emitDjotAst(e, document, context, DjotLocalContext{}, trimPadding(document, tokens[1:tokens[0].JumpToPair]))
+	if endnotes, ok := buildEndnotes(context, p.options.References); ok && !context.disableEndnotes {
+		e.subtree(endnotes)
+	}
+	e.leave()
+	return DjotDocument{Metadata: context.Metadata, Context: context, Diagnostics: context.diagnostics}
+}
+
+// Blocks lazily iterates over top-level blocks of the document (children of the DocumentNode, including endnotes section):
+// every block is built right before it is yielded and parsing stops as soon as iteration is stopped.
+// Similarly to ParseEvents, content of the FootnoteDefNode attached to the FootnoteReferenceNode is always empty
+func (p *Parser) Blocks(document []byte) iter.Seq[TreeNode[DjotNode]] {
+	return func(yield func(TreeNode[DjotNode]) bool) {
+		builder := newTreeEmitter()
+		p.ParseEvents(document, func(event Event) bool {
+			builder.replay(event)
+			// builder stack holds root pseudo-node and DocumentNode when top-level block is completed
+			if len(builder.stack) != 2 || len(builder.stack[1].Children) == 0 {
+				return true
+			}
+			block := builder.stack[1].Children[0]
+			builder.stack[1].Children = builder.stack[1].Children[:0]
+			return yield(block)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package djot_parser
+
+import (
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// Options configures BuildDjotAst; zero value corresponds to the plain djot syntax
+type Options struct {
+	InlineExtensions []InlineExtension
+	BlockExtensions  []BlockExtension
+	// References are shared definitions available to the document in addition to its own (local definitions take precedence)
+	References References
+	// Slugger creates heading section ids from the heading text (CreateSectionId is used if nil); ids are de-duplicated with -1, -2, ... suffixes
+	Slugger Slugger
+	// AttachFootnotes wraps every footnote reference into FootnoteReferenceNode which holds both reference link and footnote content
+	// (so renderer can emit sidenotes or per-section endnotes); document endnotes section is produced as usual
+	AttachFootnotes bool
+	// SmartPunctuation configures conversion of straight quotes, dashes and ellipses (English quotes are used by default)
+	SmartPunctuation SmartPunctuation
+	// IdPrefix is prepended to the generated ids (sections and footnotes) to avoid collisions when multiple documents rendered on one page
+	IdPrefix string
+	// Limits bounds parsing cost for the untrusted input (see BuildDjotDocumentContext)
+	Limits Limits
+	// DisableSections keeps headings in place instead of wrapping them together with the following content into SectionNode-s
+	// (section id is assigned to the HeadingNode in this case)
+	DisableSections bool
+	// DisableEndnotes omits section with footnotes at the end of the document (footnotes are still numbered and can be attached with AttachFootnotes)
+	DisableEndnotes bool
+	// DisableHeadingReferences disables implicit reference definitions for the headings ([Heading text][] links)
+	DisableHeadingReferences bool
+	// FrontMatter enables metadata block at the start of the document: either fenced with --- lines or raw block with MetadataRawFormat
+	// (otherwise such blocks are rendered as regular thematic breaks / raw blocks)
+	FrontMatter bool
+	// SourcePositions fills TreeNode.Source of the block-level nodes and DjotDocument.Lines (events don't carry source positions)
+	SourcePositions bool
+}
+
+// InlineExtension binds custom inline syntax recognized by the tokenizer to the AST node of type Node:
+//   - children of the paired extension are parsed as regular inline content
+//   - children of the verbatim or unpaired extension are single TextNode with the raw token content
+//
+// Attributes (optional) calculates node attributes from the matched tokens (close token is empty for unpaired extension)
+// Node conversion is driven by the regular ConversionRegistry, so extension must either reuse existing node type
+// or provide converter for its own node type (see NewDjotNode)
+type InlineExtension struct {
+	djot_tokenizer.InlineExtension
+	Node       DjotNode
+	Attributes func(document []byte, open, close tokenizer.Token[djot_tokenizer.DjotToken]) tokenizer.Attributes
+}
+
+// BlockExtension binds custom block syntax recognized by the tokenizer to the AST node of type Node:
+//   - children of the container extension are parsed as regular blocks
+//   - children of the verbatim extension are TextNode-s with the raw lines of the block content
+//
+// Node attributes are collected from the block attributes ({...} before the block) and open token attributes
+type BlockExtension struct {
+	djot_tokenizer.BlockExtension
+	Node DjotNode
+}
+
+func (o Options) tokenizerOptions() djot_tokenizer.Options {
+	options := djot_tokenizer.Options{
+		MaxNestingDepth:     o.Limits.MaxNestingDepth,
+		MaxInlineDelimiters: o.Limits.MaxInlineDelimiters,
+		FrontMatter:         o.FrontMatter,
+	}
+	for _, extension := range o.InlineExtensions {
+		options.InlineExtensions = append(options.InlineExtensions, extension.InlineExtension)
+	}
+	for _, extension := range o.BlockExtensions {
+		options.BlockExtensions = append(options.BlockExtensions, extension.BlockExtension)
+	}
+	return options
+}
+
+// Validate returns error if some extension violates the extension contract (see djot_tokenizer.Options.Validate)
+func (o Options) Validate() error { return o.tokenizerOptions().Validate() }
+
+func (o Options) BuildDjotAst(document []byte) []TreeNode[DjotNode] {
+	return NewParser(o).Parse(document).Nodes
+}
+
+func (o Options) BuildDjotDocument(document []byte) DjotDocument {
+	return NewParser(o).Parse(document)
+}
+
+func emitInlineExtension(
+	e *astEmitter,
+	document []byte,
+	context DjotContext,
+	extension InlineExtension,
+	attributes tokenizer.Attributes,
+	list tokenizer.TokenList[djot_tokenizer.DjotToken],
+) {
+	openToken, closeToken := list[0], tokenizer.Token[djot_tokenizer.DjotToken]{}
+	if openToken.JumpToPair > 0 {
+		closeToken = list[openToken.JumpToPair]
+	}
+	if extension.Attributes != nil {
+		attributes.MergeWith(extension.Attributes(document, openToken, closeToken))
+	}
+	e.enter(extension.Node, attributes)
+	if extension.MatchClose == nil {
+		e.text(openToken.Bytes(document))
+	} else if extension.Verbatim {
+		e.text(document[openToken.End:closeToken.Start])
+	} else {
+		emitDjotAst(e, document, context, DjotLocalContext{TextNode: true}, list[1:openToken.JumpToPair])
+	}
+	e.leave()
+}
+
+func emitBlockExtension(
+	e *astEmitter,
+	document []byte,
+	context DjotContext,
+	extension BlockExtension,
+	attributes tokenizer.Attributes,
+	list tokenizer.TokenList[djot_tokenizer.DjotToken],
+) {
+	e.enter(extension.Node, attributes)
+	if extension.Container {
+		emitDjotAst(e, document, context, DjotLocalContext{}, list[1:list[0].JumpToPair])
+	} else {
+		emitDjotAst(e, document, context, DjotLocalContext{TextNode: true}, trimPadding(document, list[1:list[0].JumpToPair]))
+	}
+	e.leave()
+}
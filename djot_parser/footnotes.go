@@ -0,0 +1,171 @@
+package djot_parser
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+type footnoteDefinition struct {
+	Attributes tokenizer.Attributes
+	Children   []TreeNode[DjotNode]
+}
+
+// footnoteReferenceId returns id of the n-th reference to the footnote: fnref1 for the first one, fnref1-2 for the second one, etc
+func footnoteReferenceId(context DjotContext, footnoteId, n int) string {
+	if n <= 1 {
+		return fmt.Sprintf("%vfnref%v", context.idPrefix, footnoteId)
+	}
+	return fmt.Sprintf("%vfnref%v-%v", context.idPrefix, footnoteId, n)
+}
+
+func buildDocumentNodes(
+	document []byte,
+	context DjotContext,
+	references References,
+	tokens tokenizer.TokenList[djot_tokenizer.DjotToken],
+	blocks *blockCache,
+) []TreeNode[DjotNode] {
+	e := newTreeEmitter()
+	e.blocks, e.budget = blocks, context.budget
+	emitDjotAst(e, document, context, DjotLocalContext{}, tokens)
+	nodes := e.nodes()
+	if context.attachFootnotes {
+		attachFootnotes(nodes, context, references)
+	}
+	if context.disableEndnotes {
+		return nodes
+	}
+	return appendEndnotes(nodes, context, references)
+}
+
+// footnoteContent returns copy of the footnote content from the document or from the shared references
+// (content is cloned because backlinks are appended to the last paragraph and footnote can be shared between documents)
+func footnoteContent(context DjotContext, references References, reference string) footnoteDefinition {
+	definition, ok := context.footnotes[reference]
+	if !ok {
+		definition.Children = references.Footnotes[reference]
+		definition.Attributes = tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: djot_tokenizer.ReferenceKey, Value: reference})
+	}
+	definition.Attributes = tokenizer.NewAttributes(definition.Attributes.Entries()...)
+	definition.Children = slices.Clone(definition.Children)
+	if len(definition.Children) > 0 {
+		last := &definition.Children[len(definition.Children)-1]
+		last.Children = slices.Clone(last.Children)
+	}
+	return definition
+}
+
+// attachFootnotes fills content of the FootnoteReferenceNode-s (without backlinks)
+func attachFootnotes(nodes []TreeNode[DjotNode], context DjotContext, references References) {
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Type == FootnoteReferenceNode && len(node.Children) == 2 {
+			definition := footnoteContent(context, references, node.Attributes.Get(djot_tokenizer.ReferenceKey))
+			node.Children[1].Attributes, node.Children[1].Children = definition.Attributes, definition.Children
+			context.budget.addTrees(definition.Children)
+			continue
+		}
+		attachFootnotes(node.Children, context, references)
+	}
+}
+
+// appendEndnotes adds section with all referenced footnotes (ordered by the first reference) to the end of the document:
+//   - footnote content is taken from the document or from the shared references (content of undefined footnote is empty)
+//   - footnotes without references are omitted
+func appendEndnotes(nodes []TreeNode[DjotNode], context DjotContext, references References) []TreeNode[DjotNode] {
+	if len(nodes) == 0 || nodes[0].Type != DocumentNode {
+		return nodes
+	}
+	if endnotes, ok := buildEndnotes(context, references); ok {
+		context.budget.addTrees([]TreeNode[DjotNode]{endnotes})
+		nodes[0].Children = append(nodes[0].Children, endnotes)
+	}
+	return nodes
+}
+
+// buildEndnotes creates endnotes section (see appendEndnotes); it returns false if document has no footnote references
+func buildEndnotes(context DjotContext, references References) (TreeNode[DjotNode], bool) {
+	if len(context.footnoteOrder) == 0 {
+		return TreeNode[DjotNode]{}, false
+	}
+	footnotes := make([]TreeNode[DjotNode], 0, len(context.footnoteOrder))
+	for i, reference := range context.footnoteOrder {
+		definition := footnoteContent(context, references, reference)
+		footnotes = append(footnotes, buildFootnoteItem(context, i+1, max(context.footnoteBacklinks[reference], 1), definition))
+	}
+	return TreeNode[DjotNode]{
+		Type:       SectionNode,
+		Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: RoleKey, Value: "doc-endnotes"}),
+		Children: []TreeNode[DjotNode]{
+			{Type: ThematicBreakNode},
+			{Type: OrderedListNode, Children: footnotes},
+		},
+	}, true
+}
+
+// buildFootnoteItem creates footnote list item with the backlinks to all footnote references appended to the footnote content
+func buildFootnoteItem(context DjotContext, footnoteId, backlinks int, definition footnoteDefinition) TreeNode[DjotNode] {
+	attributes, children := definition.Attributes, definition.Children
+	attributes.Set(LinkHrefKey, "#"+footnoteReferenceId(context, footnoteId, 1))
+	attributes.Set(RoleKey, "doc-backlink")
+	backrefLinkNodes := []TreeNode[DjotNode]{{
+		Type:       LinkNode,
+		Children:   []TreeNode[DjotNode]{{Type: TextNode, Text: []byte("↩︎︎")}},
+		Attributes: attributes,
+	}}
+	for n := 2; n <= backlinks; n++ {
+		backrefLinkNodes = append(backrefLinkNodes, TreeNode[DjotNode]{
+			Type: LinkNode,
+			Children: []TreeNode[DjotNode]{
+				{Type: TextNode, Text: []byte("↩︎︎")},
+				{Type: SuperscriptNode, Children: []TreeNode[DjotNode]{{Type: TextNode, Text: []byte(fmt.Sprintf("%v", n))}}},
+			},
+			Attributes: tokenizer.NewAttributes(
+				tokenizer.AttributeEntry{Key: LinkHrefKey, Value: "#" + footnoteReferenceId(context, footnoteId, n)},
+				tokenizer.AttributeEntry{Key: RoleKey, Value: "doc-backlink"},
+			),
+		})
+	}
+	if len(children) > 0 && children[len(children)-1].Type == ParagraphNode {
+		children[len(children)-1].Children = append(children[len(children)-1].Children, backrefLinkNodes...)
+	} else {
+		children = append(children, TreeNode[DjotNode]{Type: ParagraphNode, Children: backrefLinkNodes})
+	}
+	return TreeNode[DjotNode]{
+		Type: ListItemNode,
+		Children: []TreeNode[DjotNode]{{
+			Type:       FootnoteDefNode,
+			Children:   children,
+			Attributes: attributes,
+		}},
+		Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: IdKey, Value: fmt.Sprintf("%vfn%v", context.idPrefix, footnoteId)}),
+	}
+}
+
+// SectionFootnotes returns footnote references (see Options.AttachFootnotes) from the node subtree excluding nested sections
+// (only first reference is returned for every footnote)
+func SectionFootnotes(node TreeNode[DjotNode]) []TreeNode[DjotNode] {
+	footnotes := make([]TreeNode[DjotNode], 0)
+	seen := make(map[string]struct{})
+	var collect func(nodes []TreeNode[DjotNode])
+	collect = func(nodes []TreeNode[DjotNode]) {
+		for _, child := range nodes {
+			if child.Type == SectionNode {
+				continue
+			}
+			if child.Type == FootnoteReferenceNode {
+				if _, ok := seen[child.Attributes.Get(FootnoteNumberKey)]; !ok {
+					seen[child.Attributes.Get(FootnoteNumberKey)] = struct{}{}
+					footnotes = append(footnotes, child)
+				}
+				continue
+			}
+			collect(child.Children)
+		}
+	}
+	collect(node.Children)
+	return footnotes
+}
@@ -0,0 +1,289 @@
+package djot_parser
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// TextEdit replaces Range of the document with Text
+type TextEdit struct {
+	Range tokenizer.Range
+	Text  []byte
+}
+
+// IncrementalDocument is the parsed document which can be updated with text edits (see Parser.ParseIncremental).
+// Document is immutable: Edit returns new document which shares unchanged AST subtrees with the previous one,
+// so nodes of the incremental documents must not be modified in place
+type IncrementalDocument struct {
+	DjotDocument
+	parser *Parser
+	text   []byte
+	tokens tokenizer.TokenList[djot_tokenizer.DjotToken]
+	// blocks holds subtrees of the top-level blocks which can be reused by the next edit
+	blocks map[string][]TreeNode[DjotNode]
+	// retokenized is the range of the new document which was tokenized by the last edit and reused is the number of reused blocks
+	retokenized tokenizer.Range
+	reused      int
+}
+
+// ParseIncremental parses document and keeps intermediate results which allow to reparse only part of the document after the edit
+func (p *Parser) ParseIncremental(document []byte) *IncrementalDocument {
+	text := bytes.Clone(document)
+	return p.parseIncremental(nil, text, p.Tokens(text), tokenizer.Range{Start: 0, End: len(text)})
+}
+
+// Text returns current text of the document
+func (d *IncrementalDocument) Text() []byte { return d.text }
+
+// Edit applies the edit and returns updated document. Only top-level blocks affected by the edit are tokenized again
+// and AST subtrees of other blocks are reused (unless they depend on the reference definitions which were changed by the edit),
+// so the result is always the same as for the full reparse of the new text
+func (d *IncrementalDocument) Edit(edit TextEdit) *IncrementalDocument {
+	if edit.Range.Start < 0 || edit.Range.Start > edit.Range.End || edit.Range.End > len(d.text) {
+		panic(fmt.Errorf("edit range %v:%v is out of document bounds (document length is %v)", edit.Range.Start, edit.Range.End, len(d.text)))
+	}
+	text := make([]byte, 0, len(d.text)-(edit.Range.End-edit.Range.Start)+len(edit.Text))
+	text = append(text, d.text[:edit.Range.Start]...)
+	text = append(text, edit.Text...)
+	text = append(text, d.text[edit.Range.End:]...)
+	tokens, retokenized := d.retokenize(text, edit)
+	return d.parser.parseIncremental(d, text, tokens, retokenized)
+}
+
+func (p *Parser) parseIncremental(previous *IncrementalDocument, text []byte, tokens tokenizer.TokenList[djot_tokenizer.DjotToken], retokenized tokenizer.Range) *IncrementalDocument {
+	context := p.options.BuildDjotContext(text, tokens)
+	blocks := &blockCache{current: make(map[string][]TreeNode[DjotNode])}
+	if top := trimPadding(text, tokens[1:tokens[0].JumpToPair]); len(top) > 0 {
+		blocks.list, blocks.size = &top[0], len(top)
+	}
+	if previous != nil {
+		blocks.previous = previous.blocks
+		blocks.referencesChanged = !maps.EqualFunc(previous.Context.References, context.References, bytes.Equal) ||
+			!maps.EqualFunc(previous.Context.ReferenceAttributes, context.ReferenceAttributes, func(a, b tokenizer.Attributes) bool {
+				return slices.Equal(a.Entries(), b.Entries())
+			})
+	}
+	return &IncrementalDocument{
+		DjotDocument: DjotDocument{
+			Nodes:       buildDocumentNodes(text, context, p.options.References, tokens, blocks),
+			Metadata:    context.Metadata,
+			Context:     context,
+			Diagnostics: context.diagnostics,
+			Lines:       context.sourceLines(text),
+		},
+		parser:      p,
+		text:        text,
+		tokens:      tokens,
+		blocks:      blocks.current,
+		retokenized: retokenized,
+		reused:      blocks.reused,
+	}
+}
+
+var metadataFence = []byte("---")
+
+// topLevelBlock is the range of the top-level tokens [First, Last] (including attributes tokens before the block)
+type topLevelBlock struct{ First, Last int }
+
+func topLevelBlocks(tokens tokenizer.TokenList[djot_tokenizer.DjotToken]) []topLevelBlock {
+	blocks := make([]topLevelBlock, 0)
+	end := tokens[0].JumpToPair
+	for i := 1; i < end; {
+		first := i
+		for i < end-1 && tokens[i].Type == djot_tokenizer.Attribute {
+			i++
+		}
+		last := i + max(tokens[i].JumpToPair, 0)
+		blocks = append(blocks, topLevelBlock{First: first, Last: last})
+		i = last + 1
+	}
+	return blocks
+}
+
+// retokenize tokenizes the text of the top-level blocks affected by the edit together with the unchanged neighbour blocks.
+// Tokens of the neighbours must be the same as before (otherwise edit affects more blocks, e.g. it opens the code block)
+// and window of the blocks is extended until this condition holds
+//
+// Front matter depends on the closing fence which can be anywhere in the document, so document which starts with the fence
+// (before or after the edit) is always tokenized from scratch; fragments in the middle of the document never have front matter
+func (d *IncrementalDocument) retokenize(text []byte, edit TextEdit) (tokenizer.TokenList[djot_tokenizer.DjotToken], tokenizer.Range) {
+	options := d.parser.tokenizerOptions
+	if options.FrontMatter && (bytes.HasPrefix(d.text, metadataFence) || bytes.HasPrefix(text, metadataFence)) {
+		return options.BuildDjotTokens(text), tokenizer.Range{Start: 0, End: len(text)}
+	}
+	tokens, blocks := d.tokens, topLevelBlocks(d.tokens)
+	delta := len(text) - len(d.text)
+	// blocks[first:last+1] are the blocks touched by the edit
+	first := 0
+	for first < len(blocks) && tokens[blocks[first].Last].End < edit.Range.Start {
+		first++
+	}
+	last := len(blocks) - 1
+	for last >= 0 && tokens[blocks[last].First].Start > edit.Range.End {
+		last--
+	}
+	for margin := 1; ; margin *= 2 {
+		before, after := first-margin, last+margin
+		if before < 0 && after >= len(blocks) {
+			return options.BuildDjotTokens(text), tokenizer.Range{Start: 0, End: len(text)}
+		}
+		start, end := 0, len(text)
+		if before >= 0 {
+			start = tokens[blocks[before].First].Start
+		}
+		if after < len(blocks) {
+			end = tokens[blocks[after].Last].End + delta
+		}
+		fragmentOptions := options
+		fragmentOptions.FrontMatter = options.FrontMatter && start == 0
+		fragment := fragmentOptions.BuildDjotTokens(text[start:end])
+		fragmentBlocks := topLevelBlocks(fragment)
+		if len(fragmentBlocks) == 0 {
+			continue
+		}
+		if before >= 0 && !sameTokens(tokens[blocks[before].First:blocks[before].Last+1], fragment[fragmentBlocks[0].First:fragmentBlocks[0].Last+1], -start) {
+			continue
+		}
+		lastFragment := fragmentBlocks[len(fragmentBlocks)-1]
+		if after < len(blocks) && !sameTokens(tokens[blocks[after].First:blocks[after].Last+1], fragment[lastFragment.First:lastFragment.Last+1], delta-start) {
+			continue
+		}
+		closeIndex := tokens[0].JumpToPair
+		result := make(tokenizer.TokenList[djot_tokenizer.DjotToken], 0, len(tokens)+len(fragment))
+		if before >= 0 {
+			result = append(result, tokens[:blocks[before].First]...)
+		} else {
+			result = append(result, fragment[0])
+		}
+		result = appendShifted(result, fragment[1:fragment[0].JumpToPair], start)
+		if after < len(blocks) {
+			result = appendShifted(result, tokens[blocks[after].Last+1:closeIndex+1], delta)
+		} else {
+			result = appendShifted(result, fragment[fragment[0].JumpToPair:], start)
+		}
+		result[0].JumpToPair = len(result) - 1
+		result[len(result)-1].JumpToPair = -(len(result) - 1)
+		return result, tokenizer.Range{Start: start, End: end}
+	}
+}
+
+// sameTokens returns true if tokens are equal to the expected tokens shifted by the offset
+func sameTokens(expected, tokens []tokenizer.Token[djot_tokenizer.DjotToken], offset int) bool {
+	if len(expected) != len(tokens) {
+		return false
+	}
+	for i := range expected {
+		a, b := expected[i], tokens[i]
+		if a.Type != b.Type || a.JumpToPair != b.JumpToPair || a.Start+offset != b.Start || a.End+offset != b.End ||
+			!slices.Equal(a.Attributes.Entries(), b.Attributes.Entries()) {
+			return false
+		}
+	}
+	return true
+}
+
+func appendShifted(list, tokens tokenizer.TokenList[djot_tokenizer.DjotToken], offset int) tokenizer.TokenList[djot_tokenizer.DjotToken] {
+	for _, token := range tokens {
+		token.Start += offset
+		token.End += offset
+		list = append(list, token)
+	}
+	return list
+}
+
+// blockCache holds AST subtrees of the top-level blocks keyed by the block text and the context which affects the subtree
+type blockCache struct {
+	// list and size identify the list of the top-level tokens of the document
+	list     *tokenizer.Token[djot_tokenizer.DjotToken]
+	size     int
+	previous map[string][]TreeNode[DjotNode]
+	current  map[string][]TreeNode[DjotNode]
+	// referencesChanged disables reuse of the blocks with the link references
+	referencesChanged bool
+	reused            int
+}
+
+// forList returns cache only for the list of the top-level tokens
+func (c *blockCache) forList(list tokenizer.TokenList[djot_tokenizer.DjotToken]) *blockCache {
+	if c == nil || len(list) == 0 || &list[0] != c.list || len(list) != c.size {
+		return nil
+	}
+	return c
+}
+
+// key returns cache key of the block which starts at blockStart (with attributes) and has open token at the open position.
+// Blocks which depend on the other parts of the document (headings ids, tables, footnotes numbers, metadata) are never cached
+func (c *blockCache) key(
+	document []byte,
+	context DjotContext,
+	list tokenizer.TokenList[djot_tokenizer.DjotToken],
+	blockStart, open int,
+	insertedNodeType DjotNode,
+	isSparseList bool,
+) (key string, cacheable bool, reusable bool) {
+	openToken := list[open]
+	if blockStart == 0 || openToken.JumpToPair <= 0 || openToken.Start == context.metadataStart {
+		return "", false, false
+	}
+	switch openToken.Type {
+	case djot_tokenizer.HeadingBlock, djot_tokenizer.PipeTableBlock, djot_tokenizer.PipeTableCaptionBlock:
+		return "", false, false
+	}
+	closeToken := list[open+openToken.JumpToPair]
+	reusable = true
+	for _, token := range list[open : open+openToken.JumpToPair] {
+		switch token.Type {
+		case djot_tokenizer.FootnoteReferenceInline, djot_tokenizer.FootnoteDefBlock, djot_tokenizer.HeadingBlock:
+			// footnote numbers and heading ids (nested heading, e.g. in the list item) depend on the rest of the document
+			return "", false, false
+		case djot_tokenizer.LinkReferenceInline:
+			// block is built with the new reference definitions, so it still can be reused by the next edit
+			reusable = !c.referencesChanged
+		}
+	}
+	// list items depend on the list type and density; last list item of the document has additional line break
+	if openToken.Type != djot_tokenizer.ListItemBlock {
+		insertedNodeType, isSparseList = 0, false
+	}
+	start := list[blockStart].Start
+	key = fmt.Sprintf("%v:%v:%v:%s", insertedNodeType, isSparseList, closeToken.End == len(document), document[start:closeToken.End])
+	// same text can be tokenized differently depending on the preceding blocks (e.g. {.cls} line is either block attributes
+	// or part of the paragraph), so the key also holds tokens of the block relative to its start
+	signature := make([]byte, 0, 8*(open+openToken.JumpToPair+1-blockStart))
+	for _, token := range list[blockStart : open+openToken.JumpToPair+1] {
+		signature = strconv.AppendInt(append(signature, ':'), int64(token.Type), 10)
+		signature = strconv.AppendInt(append(signature, ','), int64(token.JumpToPair), 10)
+		signature = strconv.AppendInt(append(signature, ','), int64(token.Start-start), 10)
+		signature = strconv.AppendInt(append(signature, ','), int64(token.End-start), 10)
+		for _, entry := range token.Attributes.Entries() {
+			signature = strconv.AppendQuote(strconv.AppendQuote(append(signature, ','), entry.Key), entry.Value)
+		}
+	}
+	key += string(signature)
+	if context.sourcePositions {
+		// source ranges of the nodes are valid only at the same offset
+		key = fmt.Sprintf("%v:%v", start, key)
+	}
+	return key, true, reusable
+}
+
+func (c *blockCache) reuse(e *astEmitter, key string) bool {
+	nodes, ok := c.previous[key]
+	if !ok {
+		return false
+	}
+	for _, node := range nodes {
+		e.subtree(node)
+	}
+	c.current[key] = nodes
+	c.reused++
+	return true
+}
+
+func (c *blockCache) store(key string, nodes []TreeNode[DjotNode]) { c.current[key] = nodes }
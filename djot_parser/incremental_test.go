@@ -0,0 +1,123 @@
+package djot_parser
+
+import (
+	"math/rand"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// incrementalSnippets are inserted by the random edits: they open and close different blocks and inline elements
+var incrementalSnippets = []string{
+	"", " ", "\n", "\n\n", "text", "*", "_", "`", "$`", "# ", "## ", "- ", "1. ", "- [ ] ", ": ", "> ", "```", "```\n", ":::", "::: note\n",
+	"---\n", "title: x\n", "{#id}", "{.cls}\n", "[a][]", "[a]: /url\n", "[^1]", "[^1]: note\n", "| a | b |\n", "|---|---|\n", "^ caption\n", "\"", "'", "...",
+}
+
+func requireIncrementalEqual(t *testing.T, parser *Parser, document *IncrementalDocument) {
+	text := document.Text()
+	expected := parser.Parse(text)
+	require.Equal(t, parser.Tokens(text), document.tokens, "document: %q", text)
+	require.Equal(t, expected.Nodes, document.Nodes, "document: %q", text)
+	require.Equal(t, expected.Diagnostics, document.Diagnostics, "document: %q", text)
+	require.Equal(t, expected.Metadata, document.Metadata, "document: %q", text)
+}
+
+func TestIncrementalDocument(t *testing.T) {
+	t.Run("reuse", func(t *testing.T) {
+		parser := NewParser(Options{})
+		text := "# Title\n\nfirst *paragraph*\n\n- a\n- b\n\nsecond [link][a]\n\nthird paragraph\n\n[a]: /url\n"
+		document := parser.ParseIncremental([]byte(text))
+		start := strings.Index(text, "third")
+		edited := document.Edit(TextEdit{Range: tokenizer.Range{Start: start, End: start + len("third")}, Text: []byte("3rd")})
+		requireIncrementalEqual(t, parser, edited)
+		// retokenized range includes unchanged neighbour blocks: paragraph before the edit and reference definition after it
+		require.Equal(t, tokenizer.Range{Start: strings.Index(text, "second"), End: len(edited.Text())}, edited.retokenized)
+		// paragraphs, list items, link with unchanged reference and the reference definition itself
+		require.Equal(t, 5, edited.reused)
+		require.Equal(t, text, string(document.Text()))
+
+		start = strings.Index(string(edited.Text()), "/url")
+		redefined := edited.Edit(TextEdit{Range: tokenizer.Range{Start: start, End: start + len("/url")}, Text: []byte("/other")})
+		requireIncrementalEqual(t, parser, redefined)
+		// paragraph with the link is rebuilt because the reference definition changed
+		require.Equal(t, 4, redefined.reused)
+	})
+	t.Run("block attributes", func(t *testing.T) {
+		// paragraph "{.cls}\n}" had inline attributes before the edit and block attributes after it
+		parser := NewParser(Options{})
+		document := parser.ParseIncremental([]byte("[^1]: note1. ]  - x\n[- [ ] `*{::: \n\n{.cls}\n}"))
+		requireIncrementalEqual(t, parser, document.Edit(TextEdit{Range: tokenizer.Range{Start: 6, End: 11}, Text: []byte("## : ")}))
+	})
+	t.Run("front matter", func(t *testing.T) {
+		parser := NewParser(Options{FrontMatter: true})
+		document := parser.ParseIncremental([]byte("---\n\ntitle: x\n\nbody\n\nmore\n"))
+		closed := document.Edit(TextEdit{Range: tokenizer.Range{Start: 15, End: 15}, Text: []byte("---\n")})
+		requireIncrementalEqual(t, parser, closed)
+		require.Equal(t, Metadata{"title": "x"}, closed.Metadata)
+		opened := closed.Edit(TextEdit{Range: tokenizer.Range{Start: 15, End: 19}})
+		requireIncrementalEqual(t, parser, opened)
+		requireIncrementalEqual(t, parser, opened.Edit(TextEdit{Range: tokenizer.Range{Start: 0, End: 4}}))
+		// thematic break in the middle of the document is never front matter
+		document = parser.ParseIncremental([]byte("a\n\n---\ntitle: x\n\nb\n"))
+		requireIncrementalEqual(t, parser, document.Edit(TextEdit{Range: tokenizer.Range{Start: 16, End: 16}, Text: []byte("---\n")}))
+	})
+	t.Run("nested heading", func(t *testing.T) {
+		// id of the heading in the list item depends on the heading which is removed by the edit
+		parser := NewParser(Options{})
+		document := parser.ParseIncremental([]byte("## a\n\n- # a\n"))
+		requireIncrementalEqual(t, parser, document.Edit(TextEdit{Range: tokenizer.Range{Start: 0, End: 3}}))
+	})
+	t.Run("random documents", func(t *testing.T) {
+		random := rand.New(rand.NewSource(1))
+		for _, options := range []Options{{}, {AttachFootnotes: true}, {FrontMatter: true}} {
+			parser := NewParser(options)
+			for i := 0; i < 300; i++ {
+				var text strings.Builder
+				if options.FrontMatter && random.Intn(2) == 0 {
+					text.WriteString("---\n")
+				}
+				for j := 0; j < 12; j++ {
+					text.WriteString(incrementalSnippets[random.Intn(len(incrementalSnippets))])
+				}
+				document := parser.ParseIncremental([]byte(text.String()))
+				for j := 0; j < 10; j++ {
+					length := len(document.Text())
+					start := random.Intn(length + 1)
+					end := min(length, start+random.Intn(8))
+					edit := TextEdit{Range: tokenizer.Range{Start: start, End: end}, Text: []byte(incrementalSnippets[random.Intn(len(incrementalSnippets))])}
+					document = document.Edit(edit)
+					requireIncrementalEqual(t, parser, document)
+				}
+			}
+		}
+	})
+	t.Run("random edits", func(t *testing.T) {
+		dir, err := os.ReadDir(examplesDir)
+		require.Nil(t, err)
+		random := rand.New(rand.NewSource(1))
+		for _, options := range []Options{{}, {AttachFootnotes: true, DisableSections: true}, {SourcePositions: true}, {FrontMatter: true}} {
+			parser := NewParser(options)
+			for _, entry := range dir {
+				if !strings.HasSuffix(entry.Name(), ".djot") {
+					continue
+				}
+				text, err := os.ReadFile(path.Join(examplesDir, entry.Name()))
+				require.Nil(t, err)
+				document := parser.ParseIncremental(text)
+				for i := 0; i < 20; i++ {
+					length := len(document.Text())
+					start := random.Intn(length + 1)
+					end := min(length, start+random.Intn(8))
+					edit := TextEdit{Range: tokenizer.Range{Start: start, End: end}, Text: []byte(incrementalSnippets[random.Intn(len(incrementalSnippets))])}
+					document = document.Edit(edit)
+					requireIncrementalEqual(t, parser, document)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package djot_parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInputTooLarge = errors.New("djot document is too large")
+	ErrTooManyNodes  = errors.New("djot document has too many nodes")
+)
+
+// Limits bounds resources spent on the parsing of untrusted input (zero value of the field means no limit):
+//   - MaxInputSize and MaxNodes abort BuildDjotDocumentContext with ErrInputTooLarge / ErrTooManyNodes
+//     (nodes are counted while AST is built, so parsing stops as soon as the limit is exceeded)
+//   - MaxNestingDepth (blocks) and MaxInlineDelimiters (simultaneously open inline elements) are applied by the tokenizer:
+//     markup beyond the limit is kept as plain text, so parsing time is linear in the input size and AST depth is bounded
+type Limits struct {
+	MaxInputSize        int
+	MaxNestingDepth     int
+	MaxInlineDelimiters int
+	MaxNodes            int
+}
+
+// DefaultLimits are reasonable limits for the user-generated content (comments, posts, etc.)
+var DefaultLimits = Limits{
+	MaxInputSize:        1 << 20,
+	MaxNestingDepth:     32,
+	MaxInlineDelimiters: 64,
+	MaxNodes:            1 << 20,
+}
+
+// parseBudget is shared between the tokenizer (see djot_tokenizer.Options.Interrupt) and all copies of DjotContext:
+// it interrupts parsing when parsing context is done or when AST has more than maxNodes nodes
+type parseBudget struct {
+	ctx      context.Context
+	steps    int
+	nodes    int
+	maxNodes int
+	err      error
+}
+
+// parseBudgetCheckInterval is the number of processed tokens between checks of the parsing context
+const parseBudgetCheckInterval = 1024
+
+func (b *parseBudget) exhausted() bool {
+	if b == nil {
+		return false
+	}
+	if b.err == nil && b.steps%parseBudgetCheckInterval == 0 {
+		b.err = b.ctx.Err()
+	}
+	b.steps++
+	return b.err != nil
+}
+
+// addNode accounts new AST node and interrupts parsing if there are too many of them
+func (b *parseBudget) addNode() { b.addNodes(1) }
+
+func (b *parseBudget) addNodes(count int) {
+	if b == nil {
+		return
+	}
+	b.nodes += count
+	if b.err == nil && b.maxNodes > 0 && b.nodes > b.maxNodes {
+		b.err = fmt.Errorf("%w: more than %v nodes", ErrTooManyNodes, b.maxNodes)
+	}
+}
+
+// addTrees accounts nodes which are created after AST construction (attached footnotes and endnotes)
+func (b *parseBudget) addTrees(nodes []TreeNode[DjotNode]) {
+	if b == nil || b.err != nil {
+		return
+	}
+	b.addNodes(countNodes(nodes))
+}
+
+// BuildDjotDocumentContext is the BuildDjotDocument version for untrusted input (see Parser.ParseContext)
+func (o Options) BuildDjotDocumentContext(ctx context.Context, document []byte) (DjotDocument, error) {
+	return NewParser(o).ParseContext(ctx, document)
+}
+
+func countNodes(nodes []TreeNode[DjotNode]) int {
+	count := 0
+	for _, node := range nodes {
+		node.Traverse(func(TreeNode[DjotNode]) { count++ })
+	}
+	return count
+}
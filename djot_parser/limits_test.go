@@ -0,0 +1,151 @@
+package djot_parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func countNodesOfType(nodes []TreeNode[DjotNode], nodeType DjotNode) int {
+	count := 0
+	for _, node := range nodes {
+		node.Traverse(func(node TreeNode[DjotNode]) {
+			if node.Type == nodeType {
+				count++
+			}
+		})
+	}
+	return count
+}
+
+func TestLimits(t *testing.T) {
+	t.Run("input size", func(t *testing.T) {
+		_, err := Options{Limits: Limits{MaxInputSize: 4}}.BuildDjotDocumentContext(context.Background(), []byte("hello"))
+		require.ErrorIs(t, err, ErrInputTooLarge)
+		_, err = Options{Limits: Limits{MaxInputSize: 5}}.BuildDjotDocumentContext(context.Background(), []byte("hello"))
+		require.Nil(t, err)
+	})
+	t.Run("nodes", func(t *testing.T) {
+		// document, section, heading, text, paragraph, emphasis, text
+		document := []byte("# a\n\n_b_")
+		_, err := Options{Limits: Limits{MaxNodes: 6}}.BuildDjotDocumentContext(context.Background(), document)
+		require.ErrorIs(t, err, ErrTooManyNodes)
+		_, err = Options{Limits: Limits{MaxNodes: 7}}.BuildDjotDocumentContext(context.Background(), document)
+		require.Nil(t, err)
+	})
+	t.Run("nodes with footnotes", func(t *testing.T) {
+		// attached footnotes and endnotes are counted too, so the limit matches the size of the resulting AST
+		document := []byte("# a\n\nb[^x] c[^x] d[^y]\n\n[^x]: _e_\n\n  f\n")
+		for _, options := range []Options{{}, {AttachFootnotes: true}} {
+			count := countNodes(options.BuildDjotDocument(document).Nodes)
+			options.Limits.MaxNodes = count - 1
+			_, err := options.BuildDjotDocumentContext(context.Background(), document)
+			require.ErrorIs(t, err, ErrTooManyNodes)
+			options.Limits.MaxNodes = count
+			_, err = options.BuildDjotDocumentContext(context.Background(), document)
+			require.Nil(t, err)
+		}
+	})
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := Options{}.BuildDjotDocumentContext(ctx, []byte("hello"))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("nesting depth", func(t *testing.T) {
+		document, err := Options{Limits: Limits{MaxNestingDepth: 3}}.BuildDjotDocumentContext(context.Background(), []byte("> > > > > deep"))
+		require.Nil(t, err)
+		require.Equal(t, 3, countNodesOfType(document.Nodes, QuoteNode))
+		require.Equal(t, "> > deep", string(document.Nodes[0].FullText()))
+	})
+	t.Run("inline delimiters", func(t *testing.T) {
+		document, err := Options{Limits: Limits{MaxInlineDelimiters: 1}}.BuildDjotDocumentContext(context.Background(), []byte("*_[a](b)_*"))
+		require.Nil(t, err)
+		require.Equal(t, 1, countNodesOfType(document.Nodes, StrongNode))
+		require.Equal(t, 0, countNodesOfType(document.Nodes, EmphasisNode))
+		require.Equal(t, 0, countNodesOfType(document.Nodes, LinkNode))
+		require.Equal(t, "_[a](b)_", string(document.Nodes[0].FullText()))
+	})
+}
+
+type countingContext struct {
+	context.Context
+	calls, cancelAt int
+}
+
+// Err reports cancellation starting from the cancelAt call, so the test can cancel parsing at the exact check
+func (c *countingContext) Err() error {
+	c.calls++
+	if c.calls >= c.cancelAt {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestInterruptedParsing(t *testing.T) {
+	document := []byte(strings.Repeat("_[{*", 20_000))
+	t.Run("tokenization", func(t *testing.T) {
+		ctx := &countingContext{Context: context.Background(), cancelAt: 2}
+		_, err := Options{}.BuildDjotDocumentContext(ctx, document)
+		require.ErrorIs(t, err, context.Canceled)
+		// context is checked once before tokenization and once per parseBudgetCheckInterval steps of the tokenizer
+		require.Equal(t, 2, ctx.calls)
+	})
+	t.Run("nodes", func(t *testing.T) {
+		_, err := Options{Limits: Limits{MaxNodes: 100}}.BuildDjotDocumentContext(context.Background(), []byte(strings.Repeat("_a_ ", 20_000)))
+		require.ErrorIs(t, err, ErrTooManyNodes)
+		require.ErrorContains(t, err, "more than 100 nodes")
+	})
+}
+
+var pathologicalPatterns = []string{
+	"_[{*",
+	"[a](",
+	"[a]{",
+	"*a ",
+	"> ",
+	"> - ",
+	"- ",
+	"1. ",
+	"::: x\n",
+	"[^",
+	"\"",
+}
+
+func parsingAllocs(t *testing.T, options Options, document []byte) float64 {
+	return testing.AllocsPerRun(1, func() {
+		_, err := options.BuildDjotDocumentContext(context.Background(), document)
+		require.Nil(t, err)
+	})
+}
+
+func TestPathologicalInputs(t *testing.T) {
+	options := Options{Limits: DefaultLimits}
+	for _, pattern := range pathologicalPatterns {
+		t.Run(pattern, func(t *testing.T) {
+			small := parsingAllocs(t, options, []byte(strings.Repeat(pattern, 5_000)))
+			large := parsingAllocs(t, options, []byte(strings.Repeat(pattern, 20_000)))
+			t.Logf("%q: %v -> %v allocations", pattern, small, large)
+			// 4x larger input must take roughly 4x more allocations (quadratic parsing takes 16x more)
+			require.LessOrEqual(t, large, 5*small)
+		})
+	}
+}
+
+func BenchmarkPathologicalInputs(b *testing.B) {
+	options := Options{Limits: DefaultLimits}
+	for _, pattern := range pathologicalPatterns {
+		for _, repeat := range []int{5_000, 20_000} {
+			document := []byte(strings.Repeat(pattern, repeat))
+			b.Run(fmt.Sprintf("%q/%v", pattern, repeat), func(b *testing.B) {
+				b.SetBytes(int64(len(document)))
+				for i := 0; i < b.N; i++ {
+					_, _ = options.BuildDjotDocumentContext(context.Background(), document)
+				}
+			})
+		}
+	}
+}
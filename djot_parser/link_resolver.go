@@ -0,0 +1,129 @@
+package djot_parser
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// LinkResolver receives link or image node together with its URL (href or src attribute)
+// and returns final URL and extra attributes which must be added to the node (e.g. rel or target)
+//
+// Resolver is called for all links with destination, including intra-document links (#fragment) and footnote references
+type LinkResolver func(node TreeNode[DjotNode], url string) (string, []tokenizer.AttributeEntry)
+
+func linkUrlKey(node DjotNode) string {
+	switch node {
+	case LinkNode:
+		return LinkHrefKey
+	case ImageNode:
+		return ImgSrcKey
+	}
+	return ""
+}
+
+// ResolveNode returns copy of the node with resolved URL (AST node is not modified because it can be converted multiple times)
+func (r LinkResolver) ResolveNode(node TreeNode[DjotNode]) TreeNode[DjotNode] {
+	key := linkUrlKey(node.Type)
+	if key == "" {
+		return node
+	}
+	link, ok := node.Attributes.TryGet(key)
+	if !ok {
+		return node
+	}
+	resolved, extra := r(node, link)
+	attributes := tokenizer.NewAttributes(node.Attributes.Entries()...)
+	attributes.Set(key, resolved)
+	for _, entry := range extra {
+		attributes.Set(entry.Key, entry.Value)
+	}
+	node.Attributes = attributes
+	return node
+}
+
+// ChainLinkResolvers applies resolvers one after another (URL returned by the resolver is passed to the next one)
+func ChainLinkResolvers(resolvers ...LinkResolver) LinkResolver {
+	return func(node TreeNode[DjotNode], link string) (string, []tokenizer.AttributeEntry) {
+		var attributes []tokenizer.AttributeEntry
+		for _, resolver := range resolvers {
+			var extra []tokenizer.AttributeEntry
+			link, extra = resolver(node, link)
+			attributes = append(attributes, extra...)
+		}
+		return link, attributes
+	}
+}
+
+// IsExternalLink returns true for URLs with scheme (https://, mailto:, etc) or host (//example.com)
+func IsExternalLink(link string) bool {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return strings.Contains(link, "://")
+	}
+	return parsed.Scheme != "" || parsed.Host != ""
+}
+
+// BaseUrlResolver resolves non-external URLs of given node types (all link and image nodes if empty) against base URL
+// (e.g. BaseUrlResolver("https://cdn.example.com/", ImageNode) serves all local images from CDN)
+func BaseUrlResolver(base string, nodes ...DjotNode) LinkResolver {
+	baseUrl, err := url.Parse(base)
+	if err != nil {
+		panic(fmt.Errorf("invalid base url %v: %w", base, err))
+	}
+	return func(node TreeNode[DjotNode], link string) (string, []tokenizer.AttributeEntry) {
+		if len(nodes) > 0 && !slices.Contains(nodes, node.Type) || IsExternalLink(link) || strings.HasPrefix(link, "#") {
+			return link, nil
+		}
+		reference, err := url.Parse(link)
+		if err != nil {
+			return link, nil
+		}
+		return baseUrl.ResolveReference(reference).String(), nil
+	}
+}
+
+// DjotToHtmlResolver rewrites non-external links to the .djot files into links to the .html files
+func DjotToHtmlResolver(node TreeNode[DjotNode], link string) (string, []tokenizer.AttributeEntry) {
+	if node.Type != LinkNode || IsExternalLink(link) {
+		return link, nil
+	}
+	path, suffix := link, ""
+	if i := strings.IndexAny(link, "?#"); i != -1 {
+		path, suffix = link[:i], link[i:]
+	}
+	if !strings.HasSuffix(path, ".djot") {
+		return link, nil
+	}
+	return strings.TrimSuffix(path, ".djot") + ".html" + suffix, nil
+}
+
+// PercentEncodeResolver percent-encodes spaces, control and non-ASCII characters of the URL
+func PercentEncodeResolver(_ TreeNode[DjotNode], link string) (string, []tokenizer.AttributeEntry) {
+	var encoded strings.Builder
+	for i := 0; i < len(link); i++ {
+		if c := link[i]; c <= ' ' || c >= 0x7f {
+			_, _ = fmt.Fprintf(&encoded, "%%%02X", c)
+		} else {
+			encoded.WriteByte(c)
+		}
+	}
+	return encoded.String(), nil
+}
+
+// ExternalLinkResolver adds rel="noopener noreferrer" and target attribute (if not empty) to the external links
+func ExternalLinkResolver(target string) LinkResolver {
+	return func(node TreeNode[DjotNode], link string) (string, []tokenizer.AttributeEntry) {
+		if node.Type != LinkNode || !IsExternalLink(link) {
+			return link, nil
+		}
+		attributes := []tokenizer.AttributeEntry{{Key: "rel", Value: "noopener noreferrer"}}
+		if target != "" {
+			attributes = append(attributes, tokenizer.AttributeEntry{Key: "target", Value: target})
+		}
+		return link, attributes
+	}
+}
@@ -0,0 +1,148 @@
+package djot_parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetadataRawFormat is the format of raw block which can be used as metadata block instead of --- fences
+// (only if it's the first block of the document and Options.FrontMatter is set)
+const MetadataRawFormat = "meta"
+
+// Metadata holds document metadata (front matter) values parsed from the YAML-like key/value syntax:
+//
+//	title: Hello, world
+//	date: 2024-01-31
+//	draft: false
+//	tags: [djot, go]
+//	authors:
+//	  - Alice
+//	  - "Bob"
+//
+// Unquoted scalars are converted to bool, int64, float64 or time.Time (for dates and RFC3339 timestamps) if possible,
+// and quoted scalars are always kept as strings. Lists are stored as []any
+type Metadata map[string]any
+
+func (m Metadata) String(key string) string {
+	switch value := m[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case time.Time:
+		return value.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func (m Metadata) Bool(key string) bool {
+	value, _ := m[key].(bool)
+	return value
+}
+
+func (m Metadata) Int(key string) (int64, bool) {
+	value, ok := m[key].(int64)
+	return value, ok
+}
+
+func (m Metadata) Time(key string) (time.Time, bool) {
+	value, ok := m[key].(time.Time)
+	return value, ok
+}
+
+// Strings returns list value as strings (single scalar value is treated as list with one element)
+func (m Metadata) Strings(key string) []string {
+	switch value := m[key].(type) {
+	case nil:
+		return nil
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, Metadata{key: item}.String(key))
+		}
+		return items
+	default:
+		return []string{m.String(key)}
+	}
+}
+
+// ParseMetadata parses metadata block content; on error it returns all values parsed before the problematic line
+func ParseMetadata(content []byte) (Metadata, error) {
+	metadata := make(Metadata)
+	listKey := ""
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && listKey != "" {
+			metadata[listKey] = append(metadata[listKey].([]any), parseMetadataScalar(item))
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || key == "" || trimmed != line || strings.ContainsAny(key, " \t") {
+			return metadata, fmt.Errorf("invalid metadata at line %v: %v", i+1, line)
+		}
+		value = strings.TrimSpace(value)
+		listKey = ""
+		if value == "" {
+			metadata[key], listKey = make([]any, 0), key
+		} else if items, ok := cutMetadataList(value); ok {
+			metadata[key] = items
+		} else {
+			metadata[key] = parseMetadataScalar(value)
+		}
+	}
+	return metadata, nil
+}
+
+func cutMetadataList(value string) ([]any, bool) {
+	if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+		return nil, false
+	}
+	items := make([]any, 0)
+	for _, item := range strings.Split(value[1:len(value)-1], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, parseMetadataScalar(item))
+		}
+	}
+	return items, true
+}
+
+var metadataTimeLayouts = [...]string{time.DateOnly, time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+func parseMetadataScalar(value string) any {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if value[0] == '"' {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+		}
+		return value[1 : len(value)-1]
+	}
+	if comment := strings.Index(value, " #"); comment != -1 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	switch value {
+	case "true", "True", "yes":
+		return true
+	case "false", "False", "no":
+		return false
+	}
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return number
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !strings.ContainsAny(value, "xXpPnN") {
+		return number
+	}
+	for _, layout := range metadataTimeLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date
+		}
+	}
+	return value
+}
@@ -0,0 +1,89 @@
+package djot_parser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetadata(t *testing.T) {
+	metadata, err := ParseMetadata([]byte(`# comment
+title: Hello: world
+author: "Jane \"JD\" Doe"
+date: 2024-01-31
+draft: true
+weight: 10 # inline comment
+ratio: 0.5
+tags: [djot, 'go', 1]
+authors:
+  - Alice
+  - "Bob"
+`))
+	require.Nil(t, err)
+	require.Equal(t, Metadata{
+		"title":   "Hello: world",
+		"author":  `Jane "JD" Doe`,
+		"date":    time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		"draft":   true,
+		"weight":  int64(10),
+		"ratio":   0.5,
+		"tags":    []any{"djot", "go", int64(1)},
+		"authors": []any{"Alice", "Bob"},
+	}, metadata)
+	require.Equal(t, "Hello: world", metadata.String("title"))
+	require.Equal(t, "", metadata.String("missing"))
+	require.True(t, metadata.Bool("draft"))
+	require.Equal(t, []string{"djot", "go", "1"}, metadata.Strings("tags"))
+	require.Equal(t, []string{"Hello: world"}, metadata.Strings("title"))
+	date, ok := metadata.Time("date")
+	require.True(t, ok)
+	require.Equal(t, 2024, date.Year())
+
+	partial, err := ParseMetadata([]byte("title: ok\n  broken line\nauthor: skipped"))
+	require.NotNil(t, err)
+	require.Equal(t, Metadata{"title": "ok"}, partial)
+}
+
+func TestDocumentMetadata(t *testing.T) {
+	options := Options{FrontMatter: true}
+	t.Run("front matter", func(t *testing.T) {
+		document := options.BuildDjotDocument([]byte("---\ntitle: Hello\ntags: [a, b]\n---\n# Heading\n\n---\n"))
+		require.Equal(t, Metadata{"title": "Hello", "tags": []any{"a", "b"}}, document.Metadata)
+		require.Equal(t, []TreeNode[DjotNode]{{Type: DocumentNode, Children: []TreeNode[DjotNode]{
+			{Type: SectionNode, Attributes: document.Nodes[0].Children[0].Attributes, Children: []TreeNode[DjotNode]{
+				{Type: HeadingNode, Attributes: document.Nodes[0].Children[0].Children[0].Attributes, Children: []TreeNode[DjotNode]{{Type: TextNode, Text: []byte("Heading")}}},
+				{Type: ThematicBreakNode},
+			}},
+		}}}, document.Nodes)
+	})
+	t.Run("raw meta block", func(t *testing.T) {
+		document := options.BuildDjotDocument([]byte("``` =meta\ntitle: Raw\n```\n\ntext\n"))
+		require.Equal(t, Metadata{"title": "Raw"}, document.Metadata)
+		require.Len(t, document.Nodes[0].Children, 1)
+		require.Equal(t, ParagraphNode, document.Nodes[0].Children[0].Type)
+	})
+	t.Run("not at the start", func(t *testing.T) {
+		for _, text := range []string{"text\n\n---\ntitle: a\n---\n", "text\n\n``` =meta\ntitle: a\n```\n", "---\ntitle: unclosed\n"} {
+			document := options.BuildDjotDocument([]byte(text))
+			require.Nil(t, document.Metadata)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		for _, text := range []string{"---\ntitle: a\n---\n", "``` =meta\ntitle: a\n```\n"} {
+			document := BuildDjotDocument([]byte(text))
+			require.Nil(t, document.Metadata)
+			require.NotEmpty(t, document.Nodes[0].Children)
+		}
+	})
+	t.Run("leading thematic break", func(t *testing.T) {
+		document := BuildDjotDocument([]byte("---\nIntro paragraph\n\n---\nbody\n"))
+		require.Nil(t, document.Metadata)
+		require.Equal(t, []TreeNode[DjotNode]{{Type: DocumentNode, Children: []TreeNode[DjotNode]{
+			{Type: ThematicBreakNode},
+			{Type: ParagraphNode, Children: []TreeNode[DjotNode]{{Type: TextNode, Text: []byte("Intro paragraph")}}},
+			{Type: ThematicBreakNode},
+			{Type: ParagraphNode, Children: []TreeNode[DjotNode]{{Type: TextNode, Text: []byte("body")}}},
+		}}}, document.Nodes)
+	})
+}
@@ -0,0 +1,111 @@
+package djot_parser
+
+import (
+	"fmt"
+	"sync"
+)
+
+// FirstUserNode is the start of the DjotNode range reserved for user-defined node types
+// All values below FirstUserNode belong to the library and can be extended with new built-in nodes in future versions
+//
+// User can either allocate new node type with NewDjotNode or pick fixed value from the reserved range and register it with RegisterDjotNode
+// (fixed values are useful when node type must be stable across processes, e.g. for serialized ASTs)
+const FirstUserNode DjotNode = 1 << 16
+
+var (
+	userNodesMutex = sync.RWMutex{}
+	userNodeNames  = make(map[DjotNode]string)
+	userNodeTypes  = make(map[string]DjotNode)
+	nextUserNode   = FirstUserNode
+)
+
+// NewDjotNode allocates new node type from the user range and registers it under the given name
+func NewDjotNode(name string) DjotNode {
+	userNodesMutex.Lock()
+	defer userNodesMutex.Unlock()
+
+	for {
+		if _, ok := userNodeNames[nextUserNode]; !ok {
+			break
+		}
+		nextUserNode++
+	}
+	node := nextUserNode
+	registerDjotNode(node, name)
+	nextUserNode++
+	return node
+}
+
+// RegisterDjotNode registers user-defined node type with the fixed value from the user range
+// Node name is used in String() and text (JSON) serialization of the node type, so it must be unique
+func RegisterDjotNode(node DjotNode, name string) DjotNode {
+	userNodesMutex.Lock()
+	defer userNodesMutex.Unlock()
+
+	registerDjotNode(node, name)
+	return node
+}
+
+func registerDjotNode(node DjotNode, name string) {
+	if node < FirstUserNode {
+		panic(fmt.Errorf("user node %v (%d) must be outside of built-in range (less than %d)", name, node, FirstUserNode))
+	}
+	if previous, ok := userNodeNames[node]; ok {
+		panic(fmt.Errorf("user node %d already registered with name %v", node, previous))
+	}
+	if _, ok := parseDjotNode(name); ok {
+		panic(fmt.Errorf("node with name %v already registered", name))
+	}
+	userNodeNames[node] = name
+	userNodeTypes[name] = node
+}
+
+func userNodeName(node DjotNode) (string, bool) {
+	userNodesMutex.RLock()
+	defer userNodesMutex.RUnlock()
+
+	name, ok := userNodeNames[node]
+	return name, ok
+}
+
+var builtinNodeTypes = func() map[string]DjotNode {
+	types := make(map[string]DjotNode)
+	for node := DjotNode(0); node <= lastBuiltinNode; node++ {
+		types[node.String()] = node
+	}
+	return types
+}()
+
+// ParseDjotNode returns node type (built-in or user-defined) by its name
+func ParseDjotNode(name string) (DjotNode, bool) {
+	userNodesMutex.RLock()
+	defer userNodesMutex.RUnlock()
+
+	return parseDjotNode(name)
+}
+
+func parseDjotNode(name string) (DjotNode, bool) {
+	if node, ok := builtinNodeTypes[name]; ok {
+		return node, true
+	}
+	node, ok := userNodeTypes[name]
+	return node, ok
+}
+
+func (n DjotNode) IsUserDefined() bool { return n >= FirstUserNode }
+
+func (n DjotNode) MarshalText() ([]byte, error) {
+	if _, ok := userNodeName(n); !ok && (n < 0 || n > lastBuiltinNode) {
+		return nil, fmt.Errorf("unexpected djot node: %d", int(n))
+	}
+	return []byte(n.String()), nil
+}
+
+func (n *DjotNode) UnmarshalText(text []byte) error {
+	node, ok := ParseDjotNode(string(text))
+	if !ok {
+		return fmt.Errorf("unexpected djot node name: %v", string(text))
+	}
+	*n = node
+	return nil
+}
@@ -0,0 +1,72 @@
+package djot_parser
+
+import (
+	"encoding/json"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// restoreUserNodes reverts registrations of the user-defined nodes made by the test (so test can be run multiple times)
+func restoreUserNodes(t *testing.T) {
+	userNodesMutex.Lock()
+	names, types, next := maps.Clone(userNodeNames), maps.Clone(userNodeTypes), nextUserNode
+	userNodesMutex.Unlock()
+	t.Cleanup(func() {
+		userNodesMutex.Lock()
+		userNodeNames, userNodeTypes, nextUserNode = names, types, next
+		userNodesMutex.Unlock()
+	})
+}
+
+func TestUserDefinedNode(t *testing.T) {
+	restoreUserNodes(t)
+	tocNode := NewDjotNode("TocNode")
+	figureNode := RegisterDjotNode(FirstUserNode+100, "FigureNode")
+	require.True(t, tocNode.IsUserDefined())
+	require.False(t, SpanNode.IsUserDefined())
+	require.Equal(t, "TocNode", tocNode.String())
+	require.Equal(t, "FigureNode", figureNode.String())
+	require.Equal(t, "DjotNode(1000)", DjotNode(1000).String())
+
+	require.Panics(t, func() { RegisterDjotNode(figureNode, "OtherNode") })
+	require.Panics(t, func() { NewDjotNode("ParagraphNode") })
+	require.Panics(t, func() { RegisterDjotNode(SpanNode+1, "SmallNode") })
+
+	t.Run("serialize", func(t *testing.T) {
+		nodes := []DjotNode{ParagraphNode, tocNode, figureNode}
+		data, err := json.Marshal(nodes)
+		require.Nil(t, err)
+		require.Equal(t, `["ParagraphNode","TocNode","FigureNode"]`, string(data))
+
+		var parsed []DjotNode
+		require.Nil(t, json.Unmarshal(data, &parsed))
+		require.Equal(t, nodes, parsed)
+
+		_, err = json.Marshal(DjotNode(1000))
+		require.NotNil(t, err)
+		require.NotNil(t, json.Unmarshal([]byte(`["UnknownNode"]`), &parsed))
+	})
+	t.Run("convert", func(t *testing.T) {
+		context := ConversionContext[*strings.Builder]{
+			Format: "text",
+			Registry: ConversionRegistry[*strings.Builder]{
+				tocNode: func(s ConversionState[*strings.Builder], next func(Children)) {
+					s.Writer.WriteString("[toc:" + s.Node.Attributes.Get("depth") + "]")
+					next(nil)
+				},
+				TextNode: func(s ConversionState[*strings.Builder], next func(Children)) { s.Writer.Write(s.Node.Text) },
+			},
+		}
+		result := context.ConvertDjot(&strings.Builder{}, TreeNode[DjotNode]{
+			Type:       tocNode,
+			Attributes: tokenizer.NewAttributes(tokenizer.AttributeEntry{Key: "depth", Value: "2"}),
+			Children:   []TreeNode[DjotNode]{{Type: TextNode, Text: []byte("content")}},
+		})
+		require.Equal(t, "[toc:2]content", result.String())
+	})
+}
@@ -0,0 +1,156 @@
+package djot_parser
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// Parser parses djot documents with the fixed Options. Parser has no mutable state,
+// so it can be created once and used from multiple goroutines (Options must not be modified after NewParser call)
+type Parser struct {
+	options          Options
+	tokenizerOptions djot_tokenizer.Options
+}
+
+var defaultParser = NewParser(Options{})
+
+// NewParser panics if options have invalid extensions (see Options.Validate)
+func NewParser(options Options) *Parser {
+	tokenizerOptions := options.tokenizerOptions()
+	if err := tokenizerOptions.Validate(); err != nil {
+		panic(fmt.Errorf("invalid djot parser options: %w", err))
+	}
+	return &Parser{options: options, tokenizerOptions: tokenizerOptions}
+}
+
+func (p *Parser) Options() Options { return p.options }
+
+// DjotDocument holds parsed AST together with the document-level data (metadata, references, footnotes, diagnostics)
+type DjotDocument struct {
+	Nodes       []TreeNode[DjotNode]
+	Metadata    Metadata
+	Context     DjotContext
+	Diagnostics []Diagnostic
+	// Lines converts source ranges of the nodes to line and column positions (set only with Options.SourcePositions)
+	Lines SourceLines
+}
+
+// References returns reference definitions available in the document (including shared and implicit heading references)
+// and footnotes defined in the document
+func (d DjotDocument) References() References {
+	references := References{
+		Links:      maps.Clone(d.Context.References),
+		Attributes: maps.Clone(d.Context.ReferenceAttributes),
+		Footnotes:  make(map[string][]TreeNode[DjotNode], len(d.Context.footnotes)),
+	}
+	for reference, definition := range d.Context.footnotes {
+		references.Footnotes[reference] = definition.Children
+	}
+	return references
+}
+
+func (p *Parser) Parse(document []byte) DjotDocument {
+	tokens := p.tokenizerOptions.BuildDjotTokens(document)
+	context := p.options.BuildDjotContext(document, tokens)
+	return DjotDocument{
+		Nodes:       buildDocumentNodes(document, context, p.options.References, tokens, nil),
+		Metadata:    context.Metadata,
+		Context:     context,
+		Diagnostics: context.diagnostics,
+		Lines:       context.sourceLines(document),
+	}
+}
+
+// Tokens returns token stream of the document (tokenizer is configured with the parser extensions and limits)
+func (p *Parser) Tokens(document []byte) tokenizer.TokenList[djot_tokenizer.DjotToken] {
+	return p.tokenizerOptions.BuildDjotTokens(document)
+}
+
+// Index returns source ranges of the document headings, definitions, references and explicit ids
+func (d DjotDocument) Index() SourceIndex { return d.Context.index }
+
+// ParseContext is the Parse version for untrusted input: it enforces Options.Limits
+// and aborts parsing (both tokenization and AST construction) with the context error as soon as ctx is done
+func (p *Parser) ParseContext(ctx context.Context, document []byte) (DjotDocument, error) {
+	limits := p.options.Limits
+	if limits.MaxInputSize > 0 && len(document) > limits.MaxInputSize {
+		return DjotDocument{}, fmt.Errorf("%w: %v bytes (limit is %v)", ErrInputTooLarge, len(document), limits.MaxInputSize)
+	}
+	budget := &parseBudget{ctx: ctx, maxNodes: limits.MaxNodes}
+	if budget.exhausted() {
+		return DjotDocument{}, budget.err
+	}
+	tokenizerOptions := p.tokenizerOptions
+	tokenizerOptions.Interrupt = budget.exhausted
+	tokens := tokenizerOptions.BuildDjotTokens(document)
+	if budget.err != nil {
+		return DjotDocument{}, budget.err
+	}
+	djotContext := p.options.BuildDjotContext(document, tokens)
+	djotContext.budget = budget
+	nodes := buildDocumentNodes(document, djotContext, p.options.References, tokens, nil)
+	if budget.err != nil {
+		return DjotDocument{}, budget.err
+	}
+	djotContext.budget = nil
+	return DjotDocument{
+		Nodes:       nodes,
+		Metadata:    djotContext.Metadata,
+		Context:     djotContext,
+		Diagnostics: djotContext.diagnostics,
+		Lines:       djotContext.sourceLines(document),
+	}, nil
+}
+
+// DiagnosticSeverity values match the severity levels of the Language Server Protocol
+type DiagnosticSeverity int
+
+const (
+	ErrorSeverity   DiagnosticSeverity = 1
+	WarningSeverity DiagnosticSeverity = 2
+)
+
+func (s DiagnosticSeverity) String() string {
+	switch s {
+	case ErrorSeverity:
+		return "error"
+	case WarningSeverity:
+		return "warning"
+	}
+	return fmt.Sprintf("DiagnosticSeverity(%d)", int(s))
+}
+
+// Diagnostic describes the problem found in the document (Range holds byte offsets of the problematic fragment):
+//   - invalid metadata block
+//   - duplicate reference or footnote definition
+//   - reference to undefined link or footnote
+type Diagnostic struct {
+	Severity DiagnosticSeverity
+	Range    tokenizer.Range
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v:%v: %v: %v", d.Range.Start, d.Range.End, d.Severity, d.Message)
+}
+
+func (context *DjotContext) diagnose(severity DiagnosticSeverity, start, end int, format string, args ...any) {
+	context.diagnostics = append(context.diagnostics, Diagnostic{
+		Severity: severity,
+		Range:    tokenizer.Range{Start: start, End: end},
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// parseMetadata parses metadata block content and reports invalid metadata (all values before the problematic line are kept)
+func (context *DjotContext) parseMetadata(content []byte, open, close tokenizer.Token[djot_tokenizer.DjotToken]) Metadata {
+	metadata, err := ParseMetadata(content)
+	if err != nil {
+		context.diagnose(WarningSeverity, open.Start, close.End, "%v", err)
+	}
+	return metadata
+}
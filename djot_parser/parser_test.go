@@ -0,0 +1,147 @@
+package djot_parser
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+func TestParser(t *testing.T) {
+	parser := NewParser(Options{IdPrefix: "p-"})
+	expected := Options{IdPrefix: "p-"}.BuildDjotAst([]byte("# Title\n\n*hello*[^1]\n\n[^1]: note"))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				require.Equal(t, expected, parser.Parse([]byte("# Title\n\n*hello*[^1]\n\n[^1]: note")).Nodes)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestParserOptions(t *testing.T) {
+	document := []byte("# Intro\n\nSee [Intro][][^1]\n\n[^1]: note")
+	t.Run("sections", func(t *testing.T) {
+		nodes := NewParser(Options{DisableSections: true, DisableEndnotes: true}).Parse(document).Nodes
+		require.Len(t, nodes, 1)
+		require.Equal(t, []DjotNode{HeadingNode, ParagraphNode}, []DjotNode{nodes[0].Children[0].Type, nodes[0].Children[1].Type})
+		require.Equal(t, "Intro", nodes[0].Children[0].Attributes.Get(IdKey))
+		require.Equal(t, []TocEntry{{Level: 1, Id: "Intro", Title: "Intro"}}, TableOfContents(nodes))
+	})
+	t.Run("endnotes", func(t *testing.T) {
+		require.Equal(t, 2, countNodesOfType(NewParser(Options{}).Parse(document).Nodes, SectionNode))
+		require.Equal(t, 1, countNodesOfType(NewParser(Options{DisableEndnotes: true}).Parse(document).Nodes, SectionNode))
+	})
+	t.Run("heading references", func(t *testing.T) {
+		parsed := NewParser(Options{DisableHeadingReferences: true}).Parse(document)
+		_, ok := parsed.References().Links["Intro"]
+		require.False(t, ok)
+		require.Equal(t, []Diagnostic{{Severity: WarningSeverity, Range: tokenizer.Range{Start: 13, End: 22}, Message: "undefined reference [Intro]"}}, parsed.Diagnostics)
+		require.Equal(t, "#Intro", string(NewParser(Options{}).Parse(document).References().Links["Intro"]))
+	})
+}
+
+func TestDiagnostics(t *testing.T) {
+	document := []byte(`---
+title: Hello
+not metadata
+---
+[a][] [b][missing] ![c][] [d]{.e}[^1][^2]
+
+[a]: /a
+[a]: /b
+[c]: /c
+
+[^1]: one
+
+[^1]: two
+`)
+	parsed := Options{FrontMatter: true}.BuildDjotDocument(document)
+	diagnostics := make([]string, 0, len(parsed.Diagnostics))
+	for _, diagnostic := range parsed.Diagnostics {
+		diagnostics = append(diagnostics, fmt.Sprintf("%v: %q", diagnostic, document[diagnostic.Range.Start:diagnostic.Range.End]))
+	}
+	require.Equal(t, []string{
+		`0:34: warning: invalid metadata at line 2: not metadata: "---\ntitle: Hello\nnot metadata\n---\n"`,
+		`85:93: warning: duplicate reference definition [a]: "[a]: /b\n"`,
+		`113:123: warning: duplicate footnote definition [^1]: "[^1]: two\n"`,
+		`71:75: warning: undefined footnote [^2]: "[^2]"`,
+		`40:52: warning: undefined reference [missing]: "[b][missing]"`,
+	}, diagnostics)
+	require.Equal(t, "Hello", parsed.Metadata.String("title"))
+}
+
+func TestParseEvents(t *testing.T) {
+	document := []byte("# Title\n\n*hello* `code`[^1]\n\n[^1]: note")
+	parser := NewParser(Options{})
+	rebuilt := newTreeEmitter()
+	parsed := parser.ParseEvents(document, func(event Event) bool {
+		rebuilt.replay(event)
+		return true
+	})
+	require.Nil(t, parsed.Nodes)
+	require.Equal(t, parser.Parse(document).Nodes, rebuilt.nodes())
+
+	events := 0
+	parser.ParseEvents(document, func(event Event) bool {
+		events++
+		return events < 3
+	})
+	require.Equal(t, 3, events)
+}
+
+func TestSourceIndex(t *testing.T) {
+	document := []byte("# Intro\n\n## Part {#p}\n\n[a][] [b][Intro][^1]{#p}\n\n[a]: /a\n\n[^1]: note\n")
+	parsed := NewParser(Options{IdPrefix: "x-"}).Parse(document)
+	index := parsed.Index()
+	require.Equal(t, []HeadingSymbol{
+		{Level: 1, Id: "x-Intro", Title: "Intro", Range: tokenizer.Range{Start: 0, End: 8}},
+		{Level: 2, Id: "x-Part", Title: "Part", Range: tokenizer.Range{Start: 9, End: 22}},
+	}, index.Headings)
+	require.Equal(t, []SourceSymbol{{Label: "a", Range: tokenizer.Range{Start: 49, End: 57}}}, index.LinkDefinitions)
+	require.Equal(t, []SourceSymbol{{Label: "1", Range: tokenizer.Range{Start: 58, End: 69}}}, index.FootnoteDefinitions)
+	require.Equal(t, []SourceSymbol{
+		{Label: "a", Range: tokenizer.Range{Start: 23, End: 28}},
+		{Label: "Intro", Range: tokenizer.Range{Start: 29, End: 39}},
+	}, index.LinkReferences)
+	require.Equal(t, []SourceSymbol{{Label: "1", Range: tokenizer.Range{Start: 39, End: 43}}}, index.FootnoteReferences)
+	require.Equal(t, []SourceSymbol{
+		{Label: "p", Range: tokenizer.Range{Start: 17, End: 21}},
+		{Label: "p", Range: tokenizer.Range{Start: 43, End: 47}},
+	}, index.Ids)
+	require.Equal(t, []Diagnostic{{Severity: WarningSeverity, Range: tokenizer.Range{Start: 43, End: 47}, Message: "duplicate id #p"}}, parsed.Diagnostics)
+}
+
+func TestSourcePositions(t *testing.T) {
+	document := NewParser(Options{SourcePositions: true, DisableSections: true}).Parse([]byte("# Привет\n\n> 😀 *quote*\n> text\n"))
+	heading, quote := document.Nodes[0].Children[0], document.Nodes[0].Children[1]
+	require.Equal(t, tokenizer.Range{Start: 0, End: 14}, heading.Source)
+	require.Equal(t, tokenizer.Range{Start: 16, End: 37}, quote.Source)
+	require.Equal(t, tokenizer.Range{Start: 18, End: 37}, quote.Children[0].Source)
+	require.Equal(t, tokenizer.Range{}, quote.Children[0].Children[0].Source)
+
+	require.Equal(t, SourcePosition{Line: 0, Column: 14, Utf16Column: 8}, document.Lines.Position(heading.Source.End))
+	require.Equal(t, SourcePosition{Line: 2, Column: 8, Utf16Column: 6}, document.Lines.Position(24))
+	require.Equal(t, SourcePosition{Line: 3, Column: 6, Utf16Column: 6}, document.Lines.Position(quote.Source.End))
+
+	var spans []string
+	context := ConversionContext[*[]string]{Registry: ConversionRegistry[*[]string]{}}
+	for _, node := range []DjotNode{DocumentNode, HeadingNode, QuoteNode, ParagraphNode, TextNode, StrongNode} {
+		context.Registry[node] = func(state ConversionState[*[]string], next func(Children)) {
+			if start, end, ok := state.SourceSpan(); ok {
+				*state.Writer = append(*state.Writer, fmt.Sprintf("%v %v:%v-%v:%v", state.Node.Type, start.Line, start.Column, end.Line, end.Column))
+			}
+			next(nil)
+		}
+	}
+	context.ConvertDjotDocument(&spans, document)
+	require.Equal(t, []string{"DocumentNode 0:0-3:6", "HeadingNode 0:0-0:14", "QuoteNode 2:0-3:6", "ParagraphNode 2:2-3:6"}, spans)
+	require.Empty(t, Options{}.BuildDjotDocument([]byte("text")).Nodes[0].Source)
+}
@@ -0,0 +1,91 @@
+package djot_parser
+
+import (
+	"bytes"
+	"maps"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// References holds reference definitions and footnotes which can be shared between multiple documents
+// (e.g. common links and glossary of the manual)
+type References struct {
+	Links      map[string][]byte
+	Attributes map[string]tokenizer.Attributes
+	// Footnotes holds content of the footnotes; only referenced footnotes are added to the document
+	Footnotes map[string][]TreeNode[DjotNode]
+}
+
+// AddLink adds reference definition, similar to the `[reference]: href` in the document
+func (r *References) AddLink(reference, href string, attributes ...tokenizer.AttributeEntry) {
+	if r.Links == nil {
+		r.Links = make(map[string][]byte)
+	}
+	if r.Attributes == nil {
+		r.Attributes = make(map[string]tokenizer.Attributes)
+	}
+	r.Links[reference] = []byte(href)
+	r.Attributes[reference] = tokenizer.NewAttributes(attributes...)
+}
+
+// Merge adds all definitions from the other references (definitions from other take precedence)
+func (r *References) Merge(other References) {
+	if len(other.Links) > 0 && r.Links == nil {
+		r.Links = make(map[string][]byte)
+	}
+	if len(other.Attributes) > 0 && r.Attributes == nil {
+		r.Attributes = make(map[string]tokenizer.Attributes)
+	}
+	if len(other.Footnotes) > 0 && r.Footnotes == nil {
+		r.Footnotes = make(map[string][]TreeNode[DjotNode])
+	}
+	maps.Copy(r.Links, other.Links)
+	maps.Copy(r.Attributes, other.Attributes)
+	maps.Copy(r.Footnotes, other.Footnotes)
+}
+
+func ParseReferences(document []byte) References { return Options{}.ParseReferences(document) }
+
+// ParseReferences collects reference definitions and footnotes from the djot document (everything else is ignored)
+func (o Options) ParseReferences(document []byte) References {
+	tokens := o.tokenizerOptions().BuildDjotTokens(document)
+	context := o.BuildDjotContext(document, tokens)
+	references := References{
+		Links:      make(map[string][]byte),
+		Attributes: make(map[string]tokenizer.Attributes),
+		Footnotes:  make(map[string][]TreeNode[DjotNode]),
+	}
+	for i := 0; i < len(tokens); i++ {
+		var attributes tokenizer.Attributes
+		for i < len(tokens) && tokens[i].Type == djot_tokenizer.Attribute {
+			attributes.MergeWith(tokens[i].Attributes)
+			i++
+		}
+		if i == len(tokens) {
+			break
+		}
+		openToken := tokens[i]
+		switch openToken.Type {
+		case djot_tokenizer.ReferenceDefBlock:
+			reference := openToken.Attributes.Get(djot_tokenizer.ReferenceKey)
+			references.Links[reference] = bytes.Trim(document[openToken.End:tokens[i+openToken.JumpToPair].Start], "\t\r\n ")
+			references.Attributes[reference] = attributes
+		case djot_tokenizer.FootnoteDefBlock:
+			reference := openToken.Attributes.Get(djot_tokenizer.ReferenceKey)
+			references.Footnotes[reference] = buildDjotAst(document, context, DjotLocalContext{}, tokens[i+1:i+openToken.JumpToPair])
+			i += openToken.JumpToPair
+		}
+	}
+	return references
+}
+
+// mergeInto adds references which are not defined in the document to the context
+func (r References) mergeInto(context *DjotContext) {
+	for reference, link := range r.Links {
+		if _, ok := context.References[reference]; !ok {
+			context.References[reference] = link
+			context.ReferenceAttributes[reference] = r.Attributes[reference]
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package djot_parser
+
+import (
+	"bytes"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// SemanticTokenType names are stable: new types can be added in the future, but existing types are never renamed or removed
+type SemanticTokenType string
+
+const (
+	// SemanticMarker is the syntax of the element: emphasis delimiters, heading and list markers, brackets, fences, etc.
+	SemanticMarker         SemanticTokenType = "marker"
+	SemanticHeading        SemanticTokenType = "heading"
+	SemanticEmphasis       SemanticTokenType = "emphasis"
+	SemanticStrong         SemanticTokenType = "strong"
+	SemanticHighlight      SemanticTokenType = "highlight"
+	SemanticInsert         SemanticTokenType = "insert"
+	SemanticDelete         SemanticTokenType = "delete"
+	SemanticSuperscript    SemanticTokenType = "superscript"
+	SemanticSubscript      SemanticTokenType = "subscript"
+	SemanticCode           SemanticTokenType = "code"
+	SemanticMath           SemanticTokenType = "math"
+	SemanticUrl            SemanticTokenType = "url"
+	SemanticReference      SemanticTokenType = "reference"
+	SemanticSymbol         SemanticTokenType = "symbol"
+	SemanticEscape         SemanticTokenType = "escape"
+	SemanticAttributeKey   SemanticTokenType = "attributeKey"
+	SemanticAttributeValue SemanticTokenType = "attributeValue"
+	SemanticComment        SemanticTokenType = "comment"
+	SemanticMetadata       SemanticTokenType = "metadata"
+)
+
+// SemanticTokenTypes lists all semantic token types; new types are appended to the end, so index of the type can be used
+// as the stable identifier (e.g. in the LSP semantic tokens legend)
+var SemanticTokenTypes = []SemanticTokenType{
+	SemanticMarker,
+	SemanticHeading,
+	SemanticEmphasis,
+	SemanticStrong,
+	SemanticHighlight,
+	SemanticInsert,
+	SemanticDelete,
+	SemanticSuperscript,
+	SemanticSubscript,
+	SemanticCode,
+	SemanticMath,
+	SemanticUrl,
+	SemanticReference,
+	SemanticSymbol,
+	SemanticEscape,
+	SemanticAttributeKey,
+	SemanticAttributeValue,
+	SemanticComment,
+	SemanticMetadata,
+}
+
+// SourcePosition is the zero-based position in the document: Column is the offset within the line in bytes (UTF-8)
+// and Utf16Column is the same offset in UTF-16 code units (used by LSP and JavaScript editors)
+type SourcePosition struct {
+	Line        int
+	Column      int
+	Utf16Column int
+}
+
+// SemanticToken is the classified range of the document; it never spans multiple lines
+type SemanticToken struct {
+	Type       SemanticTokenType
+	Range      tokenizer.Range
+	Start, End SourcePosition
+}
+
+func SemanticTokens(document []byte) []SemanticToken { return defaultParser.SemanticTokens(document) }
+
+// SemanticTokens returns non-overlapping classified ranges of the document in the document order (for syntax highlighting).
+// Plain text and whitespace are not classified, and ranges spanning multiple lines are split into the separate tokens for every line
+func (p *Parser) SemanticTokens(document []byte) []SemanticToken {
+	list := p.Tokens(document)
+	c := semanticClassifier{document: document}
+	stack := make([]tokenizer.Token[djot_tokenizer.DjotToken], 0, 16)
+	for i, token := range list {
+		switch {
+		case token.Type == djot_tokenizer.None || token.Type == djot_tokenizer.SmartSymbolInline:
+			c.add(semanticContentType(stack), token.Start, token.End)
+		case token.Type == djot_tokenizer.Attribute:
+			c.attributes(token.Start, token.End)
+		case token.Type == djot_tokenizer.Ignore || token.Type == djot_tokenizer.ThematicBreakToken:
+			c.add(SemanticMarker, token.Start, token.End)
+		case token.Type == djot_tokenizer.EscapedSymbolInline:
+			c.add(SemanticEscape, token.Start, token.End)
+		case token.JumpToPair > 0:
+			stack = append(stack, token)
+			switch token.Type {
+			case djot_tokenizer.ReferenceDefBlock, djot_tokenizer.FootnoteDefBlock:
+				// definition open token holds label: [label]: or [^label]:
+				labelStart, labelEnd := token.Start+bytes.IndexByte(document[token.Start:token.End], '[')+1, token.Start+bytes.LastIndexByte(document[token.Start:token.End], ']')
+				if token.Type == djot_tokenizer.FootnoteDefBlock {
+					labelStart++
+				}
+				c.add(SemanticMarker, token.Start, labelStart)
+				c.add(SemanticReference, labelStart, labelEnd)
+				c.add(SemanticMarker, labelEnd, token.End)
+			case djot_tokenizer.CodeBlock, djot_tokenizer.DivBlock:
+				// info string of the code block and class of the div are not covered by tokens
+				c.add(SemanticMarker, token.Start, token.End)
+				if i+1 < len(list) && list[i+1].Start > token.End {
+					c.add(SemanticAttributeValue, token.End, list[i+1].Start)
+				}
+			default:
+				c.add(SemanticMarker, token.Start, token.End)
+			}
+		case token.JumpToPair < 0:
+			stack = stack[:len(stack)-1]
+			c.add(SemanticMarker, token.Start, token.End)
+		}
+	}
+	return c.positions()
+}
+
+// semanticContentType returns type of the text inside the innermost classified element
+func semanticContentType(stack []tokenizer.Token[djot_tokenizer.DjotToken]) SemanticTokenType {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].Type {
+		case djot_tokenizer.HeadingBlock:
+			return SemanticHeading
+		case djot_tokenizer.EmphasisInline:
+			return SemanticEmphasis
+		case djot_tokenizer.StrongInline:
+			return SemanticStrong
+		case djot_tokenizer.HighlightedInline:
+			return SemanticHighlight
+		case djot_tokenizer.InsertInline:
+			return SemanticInsert
+		case djot_tokenizer.DeleteInline:
+			return SemanticDelete
+		case djot_tokenizer.SuperscriptInline:
+			return SemanticSuperscript
+		case djot_tokenizer.SubscriptInline:
+			return SemanticSubscript
+		case djot_tokenizer.VerbatimInline:
+			_, inlineMath := stack[i].Attributes.TryGet(djot_tokenizer.InlineMathKey)
+			_, displayMath := stack[i].Attributes.TryGet(djot_tokenizer.DisplayMathKey)
+			if inlineMath || displayMath {
+				return SemanticMath
+			}
+			return SemanticCode
+		case djot_tokenizer.CodeBlock:
+			return SemanticCode
+		case djot_tokenizer.MetadataBlock:
+			return SemanticMetadata
+		case djot_tokenizer.LinkUrlInline, djot_tokenizer.AutolinkInline, djot_tokenizer.ReferenceDefBlock:
+			return SemanticUrl
+		case djot_tokenizer.LinkReferenceInline, djot_tokenizer.FootnoteReferenceInline:
+			return SemanticReference
+		case djot_tokenizer.SymbolsInline:
+			return SemanticSymbol
+		case djot_tokenizer.RawFormatInline:
+			return SemanticAttributeValue
+		}
+	}
+	return ""
+}
+
+type semanticClassifier struct {
+	document []byte
+	tokens   []SemanticToken
+}
+
+// add appends classified range split by lines (surrounding whitespace of every line is excluded)
+func (c *semanticClassifier) add(tokenType SemanticTokenType, start, end int) {
+	if tokenType == "" {
+		return
+	}
+	for start < end {
+		lineEnd := start + bytes.IndexByte(c.document[start:end], '\n') + 1
+		if lineEnd == start {
+			lineEnd = end
+		}
+		c.addLine(tokenType, start, lineEnd)
+		start = lineEnd
+	}
+}
+
+func (c *semanticClassifier) addLine(tokenType SemanticTokenType, start, end int) {
+	line := c.document[start:end]
+	end -= len(line) - len(bytes.TrimRight(line, " \t\r\n"))
+	start += len(line) - len(bytes.TrimLeft(line, " \t\r\n"))
+	if len(c.tokens) > 0 {
+		// tokens of the different blocks can share the same range (e.g. closing fence of the div and its last paragraph)
+		last := &c.tokens[len(c.tokens)-1]
+		start = max(start, last.Range.End)
+		// adjacent pieces of the text are merged, but markers of the different elements are kept separate
+		if tokenType != SemanticMarker && last.Type == tokenType && last.Range.End == start && start < end {
+			last.Range.End = end
+			return
+		}
+	}
+	if start < end {
+		c.tokens = append(c.tokens, SemanticToken{Type: tokenType, Range: tokenizer.Range{Start: start, End: end}})
+	}
+}
+
+// attributes classifies attributes and comments of the {...} block
+func (c *semanticClassifier) attributes(start, end int) {
+	i := start
+	word := func(stop string) int {
+		j := i
+		for j < end && bytes.IndexByte([]byte(stop), c.document[j]) < 0 {
+			j++
+		}
+		return j
+	}
+	for i < end {
+		switch symbol := c.document[i]; {
+		case symbol == '{' || symbol == '}' || symbol == '=':
+			c.add(SemanticMarker, i, i+1)
+			i++
+		case symbol == ' ' || symbol == '\t' || symbol == '\r' || symbol == '\n':
+			i++
+		case symbol == '%':
+			j := i + 1
+			for j < end && c.document[j] != '%' && c.document[j] != '}' {
+				j++
+			}
+			if j < end && c.document[j] == '%' {
+				j++
+			}
+			c.add(SemanticComment, i, j)
+			i = j
+		case symbol == '#' || symbol == '.':
+			c.add(SemanticAttributeKey, i, i+1)
+			i++
+			j := word(" \t\r\n}")
+			c.add(SemanticAttributeValue, i, j)
+			i = j
+		case symbol == '"':
+			j := i + 1
+			for j < end && c.document[j] != '"' {
+				if c.document[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			j = min(j+1, end)
+			c.add(SemanticAttributeValue, i, j)
+			i = j
+		case i > start && c.document[i-1] == '=':
+			j := word(" \t\r\n}")
+			c.add(SemanticAttributeValue, i, j)
+			i = j
+		default:
+			j := max(word(" \t\r\n}="), i+1)
+			c.add(SemanticAttributeKey, i, j)
+			i = j
+		}
+	}
+}
+
+// positions fills line and column positions of the tokens (tokens are sorted, so document is scanned only once)
+func (c *semanticClassifier) positions() []SemanticToken {
+	offset, position := 0, SourcePosition{}
+	advance := func(target int) SourcePosition {
+		for offset < target {
+			r, size := utf8.DecodeRune(c.document[offset:])
+			if r == '\n' {
+				position = SourcePosition{Line: position.Line + 1}
+			} else {
+				position.Column += size
+				position.Utf16Column += max(utf16.RuneLen(r), 1)
+			}
+			offset += size
+		}
+		return position
+	}
+	for i := range c.tokens {
+		c.tokens[i].Start = advance(c.tokens[i].Range.Start)
+		c.tokens[i].End = advance(c.tokens[i].Range.End)
+	}
+	return c.tokens
+}
@@ -0,0 +1,65 @@
+package djot_parser
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+func TestSemanticTokens(t *testing.T) {
+	t.Run("classification", func(t *testing.T) {
+		document := []byte("# Head *em* {#id k=\"v\" %c%}\n\n> _x_ `code` $`m`\n\n- [a](/u) [r][ref] [^1] :smile: \\*\n\n``` go\ncode\n```\n\n[ref]: /url\n")
+		var tokens []string
+		for _, token := range SemanticTokens(document) {
+			tokens = append(tokens, fmt.Sprintf("%v:%q", token.Type, document[token.Range.Start:token.Range.End]))
+		}
+		require.Equal(t, []string{
+			`marker:"#"`, `heading:"Head"`, `marker:"*"`, `strong:"em"`, `marker:"*"`,
+			`marker:"{"`, `attributeKey:"#"`, `attributeValue:"id"`, `attributeKey:"k"`, `marker:"="`, `attributeValue:"\"v\""`, `comment:"%c%"`, `marker:"}"`,
+			`marker:">"`, `marker:"_"`, `emphasis:"x"`, `marker:"_"`, `marker:"` + "`" + `"`, `code:"code"`, `marker:"` + "`" + `"`,
+			`marker:"$` + "`" + `"`, `math:"m"`, `marker:"` + "`" + `"`,
+			`marker:"-"`, `marker:"["`, `marker:"]"`, `marker:"("`, `url:"/u"`, `marker:")"`,
+			`marker:"["`, `marker:"]"`, `marker:"["`, `reference:"ref"`, `marker:"]"`, `marker:"[^"`, `reference:"1"`, `marker:"]"`,
+			`marker:":"`, `symbol:"smile"`, `marker:":"`, `escape:"\\*"`,
+			`marker:"` + "```" + `"`, `attributeValue:"go"`, `code:"code"`, `marker:"` + "```" + `"`,
+			`marker:"["`, `reference:"ref"`, `marker:"]:"`, `url:"/url"`,
+		}, tokens)
+	})
+	t.Run("positions", func(t *testing.T) {
+		document := []byte("😀 *é*\n`a\nb`")
+		require.Equal(t, []SemanticToken{
+			{Type: SemanticMarker, Range: tokenRange(5, 6), Start: SourcePosition{0, 5, 3}, End: SourcePosition{0, 6, 4}},
+			{Type: SemanticStrong, Range: tokenRange(6, 8), Start: SourcePosition{0, 6, 4}, End: SourcePosition{0, 8, 5}},
+			{Type: SemanticMarker, Range: tokenRange(8, 9), Start: SourcePosition{0, 8, 5}, End: SourcePosition{0, 9, 6}},
+			{Type: SemanticMarker, Range: tokenRange(10, 11), Start: SourcePosition{1, 0, 0}, End: SourcePosition{1, 1, 1}},
+			{Type: SemanticCode, Range: tokenRange(11, 12), Start: SourcePosition{1, 1, 1}, End: SourcePosition{1, 2, 2}},
+			{Type: SemanticCode, Range: tokenRange(13, 14), Start: SourcePosition{2, 0, 0}, End: SourcePosition{2, 1, 1}},
+			{Type: SemanticMarker, Range: tokenRange(14, 15), Start: SourcePosition{2, 1, 1}, End: SourcePosition{2, 2, 2}},
+		}, SemanticTokens(document))
+	})
+	t.Run("examples", func(t *testing.T) {
+		dir, err := os.ReadDir(examplesDir)
+		require.Nil(t, err)
+		for _, entry := range dir {
+			if !strings.HasSuffix(entry.Name(), ".djot") {
+				continue
+			}
+			document, err := os.ReadFile(path.Join(examplesDir, entry.Name()))
+			require.Nil(t, err)
+			end := 0
+			for _, token := range SemanticTokens(document) {
+				require.True(t, end <= token.Range.Start && token.Range.Start < token.Range.End, "example %v: %+v", entry.Name(), token)
+				require.Equal(t, token.Start.Line, token.End.Line, "example %v: %+v", entry.Name(), token)
+				end = token.Range.End
+			}
+		}
+	})
+}
+
+func tokenRange(start, end int) tokenizer.Range { return tokenizer.Range{Start: start, End: end} }
@@ -0,0 +1,39 @@
+package djot_parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Slugger converts heading text to the section id
+type Slugger func(text string) string
+
+// GithubSlugger creates ids in the same way as GitHub does for markdown headings:
+// text is lowercased, punctuation is removed (except dashes and underscores) and spaces are replaced with dashes
+func GithubSlugger(text string) string {
+	id := strings.Builder{}
+	for _, c := range strings.TrimSpace(strings.ToLower(text)) {
+		if unicode.IsSpace(c) {
+			id.WriteRune('-')
+		} else if unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.IsMark(c) || c == '-' || c == '_' {
+			id.WriteRune(c)
+		}
+	}
+	return id.String()
+}
+
+// uniqueId appends -1, -2, ... suffix to the id if it was already used
+func uniqueId(used map[string]struct{}, id string) string {
+	if id == "" {
+		return id
+	}
+	candidate := id
+	for n := 1; ; n++ {
+		if _, ok := used[candidate]; !ok {
+			used[candidate] = struct{}{}
+			return candidate
+		}
+		candidate = fmt.Sprintf("%v-%v", id, n)
+	}
+}
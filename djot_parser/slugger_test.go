@@ -0,0 +1,21 @@
+package djot_parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugger(t *testing.T) {
+	require.Equal(t, "Hello-World-2024", CreateSectionId("Hello, World! 2024"))
+	require.Equal(t, "hello-world-2024", GithubSlugger("Hello, World! 2024"))
+	require.Equal(t, "api--v2_beta", GithubSlugger(" API & v2_beta "))
+	require.Equal(t, "ёжик-в-тумане", GithubSlugger("Ёжик в тумане!"))
+
+	used := make(map[string]struct{})
+	require.Equal(t, "a", uniqueId(used, "a"))
+	require.Equal(t, "a-1", uniqueId(used, "a"))
+	require.Equal(t, "a-1-1", uniqueId(used, "a-1"))
+	require.Equal(t, "a-2", uniqueId(used, "a"))
+	require.Equal(t, "", uniqueId(used, ""))
+}
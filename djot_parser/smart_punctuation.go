@@ -0,0 +1,76 @@
+package djot_parser
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// QuoteStyle defines replacements for the straight quotes; Apostrophe is used for the single quote between letters (don't, l'amour)
+type QuoteStyle struct {
+	DoubleOpen, DoubleClose string
+	SingleOpen, SingleClose string
+	Apostrophe              string
+}
+
+var (
+	EnglishQuotes = QuoteStyle{DoubleOpen: "“", DoubleClose: "”", SingleOpen: "‘", SingleClose: "’", Apostrophe: "’"}
+	GermanQuotes  = QuoteStyle{DoubleOpen: "„", DoubleClose: "“", SingleOpen: "‚", SingleClose: "‘", Apostrophe: "’"}
+	// FrenchQuotes separates guillemets from the quoted text with narrow no-break spaces
+	FrenchQuotes  = QuoteStyle{DoubleOpen: "«\u202F", DoubleClose: "\u202F»", SingleOpen: "‹\u202F", SingleClose: "\u202F›", Apostrophe: "’"}
+	SwedishQuotes = QuoteStyle{DoubleOpen: "”", DoubleClose: "”", SingleOpen: "’", SingleClose: "’", Apostrophe: "’"}
+)
+
+// SmartPunctuation configures conversion of the smart symbols: quotes, dashes (-- and ---) and ellipses (...)
+// If Disabled is true then symbols are kept as is (without {} direction markers); zero Quotes corresponds to EnglishQuotes
+type SmartPunctuation struct {
+	Disabled bool
+	Quotes   QuoteStyle
+}
+
+func (p SmartPunctuation) convert(document []byte, token tokenizer.Token[djot_tokenizer.DjotToken], textString string, textBytes []byte) []byte {
+	if p.Disabled {
+		return []byte(textString)
+	}
+	quotes := p.Quotes
+	if quotes == (QuoteStyle{}) {
+		quotes = EnglishQuotes
+	}
+	quoteDirection := detectQuoteDirection(document, token.Start)
+	if textString == "\"" && quoteDirection == OpenQuote {
+		return []byte(quotes.DoubleOpen)
+	} else if textString == "\"" && quoteDirection == CloseQuote {
+		return []byte(quotes.DoubleClose)
+	} else if textString == "'" && isApostrophe(document, token) {
+		return []byte(quotes.Apostrophe)
+	} else if textString == "'" && quoteDirection == OpenQuote {
+		return []byte(quotes.SingleOpen)
+	} else if textString == "'" && quoteDirection == CloseQuote {
+		return []byte(quotes.SingleClose)
+	} else if textString == "..." {
+		return []byte(`…`)
+	} else if strings.Count(textString, "-") == len(textString) {
+		if len(textString)%3 == 0 {
+			return bytes.Repeat([]byte(`—`), len(textString)/3)
+		} else if len(textString)%2 == 0 {
+			return bytes.Repeat([]byte(`–`), len(textString)/2)
+		} else {
+			return append(bytes.Repeat([]byte(`–`), (len(textString)-3)/2), []byte(`—`)...)
+		}
+	}
+	return textBytes
+}
+
+// isApostrophe returns true for the single quote (without explicit {} direction) placed between two letters
+func isApostrophe(document []byte, token tokenizer.Token[djot_tokenizer.DjotToken]) bool {
+	if token.End-token.Start != 1 {
+		return false
+	}
+	before, _ := utf8.DecodeLastRune(document[:token.Start])
+	after, _ := utf8.DecodeRune(document[token.End:])
+	return unicode.IsLetter(before) && unicode.IsLetter(after)
+}
@@ -0,0 +1,32 @@
+package djot_parser
+
+import "github.com/sivukhin/godjot/v2/tokenizer"
+
+// SourceIndex holds source ranges of the document cross-referencing constructs (headings, definitions, references and explicit ids)
+// in the document order; it is intended for the editor tooling (see djot_lsp package)
+type SourceIndex struct {
+	Headings            []HeadingSymbol
+	LinkDefinitions     []SourceSymbol
+	FootnoteDefinitions []SourceSymbol
+	LinkReferences      []SourceSymbol
+	FootnoteReferences  []SourceSymbol
+	Ids                 []SourceSymbol
+}
+
+// SourceSymbol is the labeled range of the document: reference label, footnote label or element id
+type SourceSymbol struct {
+	Label string
+	Range tokenizer.Range
+}
+
+type HeadingSymbol struct {
+	Level int
+	// Id is the id of the heading section in the rendered document (including Options.IdPrefix)
+	Id    string
+	Title string
+	Range tokenizer.Range
+}
+
+func (s *SourceIndex) symbol(symbols *[]SourceSymbol, label string, start, end int) {
+	*symbols = append(*symbols, SourceSymbol{Label: label, Range: tokenizer.Range{Start: start, End: end}})
+}
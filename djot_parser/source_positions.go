@@ -0,0 +1,91 @@
+package djot_parser
+
+import (
+	"bytes"
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// SourceLines converts byte offsets of the document to line and column positions
+type SourceLines struct {
+	document []byte
+	// starts holds offsets of the lines starts
+	starts []int
+}
+
+func NewSourceLines(document []byte) SourceLines {
+	starts := []int{0}
+	for i, c := range document {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return SourceLines{document: document, starts: starts}
+}
+
+// Position returns zero-based position of the byte offset (offset is clamped to the document bounds)
+func (l SourceLines) Position(offset int) SourcePosition {
+	offset = min(max(offset, 0), len(l.document))
+	line := sort.SearchInts(l.starts, offset+1) - 1
+	if line < 0 {
+		return SourcePosition{}
+	}
+	position := SourcePosition{Line: line, Column: offset - l.starts[line]}
+	for text := l.document[l.starts[line]:offset]; len(text) > 0; {
+		r, size := utf8.DecodeRune(text)
+		position.Utf16Column += max(utf16.RuneLen(r), 1)
+		text = text[size:]
+	}
+	return position
+}
+
+func (context DjotContext) sourceLines(document []byte) SourceLines {
+	if !context.sourcePositions {
+		return SourceLines{}
+	}
+	return NewSourceLines(document)
+}
+
+// isSourceBlock returns true if source range is assigned to the nodes created from the token
+func isSourceBlock(context DjotContext, tokenType djot_tokenizer.DjotToken) bool {
+	switch tokenType {
+	case
+		djot_tokenizer.DocumentBlock,
+		djot_tokenizer.HeadingBlock,
+		djot_tokenizer.QuoteBlock,
+		djot_tokenizer.ListItemBlock,
+		djot_tokenizer.CodeBlock,
+		djot_tokenizer.DivBlock,
+		djot_tokenizer.PipeTableBlock,
+		djot_tokenizer.ParagraphBlock,
+		djot_tokenizer.ThematicBreakToken:
+		return true
+	}
+	_, ok := context.blockExtensions[tokenType]
+	return ok
+}
+
+// sourceRange returns range of the block which starts at position without trailing whitespaces and line breaks
+func sourceRange(document []byte, list tokenizer.TokenList[djot_tokenizer.DjotToken], position int) tokenizer.Range {
+	open := list[position]
+	end := list[position+open.JumpToPair].End
+	// close token of the paragraph (or heading) is placed at the start of the next line which can belong to the parent block
+	if (open.Type == djot_tokenizer.ParagraphBlock || open.Type == djot_tokenizer.HeadingBlock) && open.JumpToPair > 1 {
+		end = list[position+open.JumpToPair-1].End
+	}
+	end = open.Start + len(bytes.TrimRight(document[open.Start:max(open.Start, end)], " \t\r\n"))
+	return tokenizer.Range{Start: open.Start, End: end}
+}
+
+// SourceSpan returns positions of the node source range start and end (exclusive); ok is false if node has no source range
+// (see Options.SourcePositions) or conversion context has no document lines (see ConversionContext.ConvertDjotDocument)
+func (state ConversionState[T]) SourceSpan() (start, end SourcePosition, ok bool) {
+	if state.Node.Source.End == 0 || len(state.Lines.starts) == 0 {
+		return SourcePosition{}, SourcePosition{}, false
+	}
+	return state.Lines.Position(state.Node.Source.Start), state.Lines.Position(state.Node.Source.End), true
+}
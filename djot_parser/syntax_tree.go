@@ -0,0 +1,247 @@
+package djot_parser
+
+import (
+	"bytes"
+	"iter"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+type SyntaxKind int
+
+const (
+	// SyntaxElement is the inner node built from the pair of open and close tokens (children include markers of both tokens)
+	SyntaxElement SyntaxKind = iota + 1
+	// SyntaxToken is the leaf built from the token (open or close marker, text, attributes, etc.)
+	SyntaxToken
+	// SyntaxTrivia is the leaf with the document bytes which are not covered by tokens (line breaks, indentation, blank lines, lazy markers, etc.)
+	SyntaxTrivia
+)
+
+// SyntaxNode is the node of the SyntaxTree: Token is the type of the element or token leaf (None for the trivia)
+// and Range is the position of the node in the original document (edited nodes keep range of the replaced fragment)
+type SyntaxNode struct {
+	Kind     SyntaxKind
+	Token    djot_tokenizer.DjotToken
+	Range    tokenizer.Range
+	Children []*SyntaxNode
+	// Text holds content of the leaf (nil for the elements)
+	Text []byte
+}
+
+// SyntaxTree is the lossless concrete syntax tree of the document: concatenation of the leaves is exactly the document source,
+// so targeted edits (RenameReference, SetHeadingText, ToggleTask) keep all untouched parts of the document byte-identical
+type SyntaxTree struct {
+	Root *SyntaxNode
+}
+
+func BuildSyntaxTree(document []byte) *SyntaxTree { return defaultParser.SyntaxTree(document) }
+
+// SyntaxTree builds lossless syntax tree from the document tokens (leaves reference the document bytes, so document must not be modified)
+func (p *Parser) SyntaxTree(document []byte) *SyntaxTree {
+	builder := syntaxBuilder{document: document}
+	root := builder.nodes(p.Tokens(document))[0]
+	if trivia := builder.trivia(len(document)); trivia != nil {
+		root.Children = append(root.Children, trivia)
+		root.Range.End = len(document)
+	}
+	return &SyntaxTree{Root: root}
+}
+
+// syntaxBuilder emits nodes in the document order: cursor is the end of the last emitted leaf
+type syntaxBuilder struct {
+	document []byte
+	cursor   int
+}
+
+// trivia returns leaf with the bytes between cursor and position (nil if there are no such bytes)
+func (b *syntaxBuilder) trivia(position int) *SyntaxNode {
+	if position <= b.cursor {
+		return nil
+	}
+	node := &SyntaxNode{Kind: SyntaxTrivia, Range: tokenizer.Range{Start: b.cursor, End: position}, Text: b.document[b.cursor:position]}
+	b.cursor = position
+	return node
+}
+
+// leaves appends leaf of the token (together with the preceding trivia) to the nodes; empty tokens are skipped
+func (b *syntaxBuilder) leaves(nodes []*SyntaxNode, token tokenizer.Token[djot_tokenizer.DjotToken]) []*SyntaxNode {
+	if trivia := b.trivia(token.Start); trivia != nil {
+		nodes = append(nodes, trivia)
+	}
+	// tokens can overlap with the already emitted leaves, so only the new part of the token is emitted
+	if token.End <= b.cursor {
+		return nodes
+	}
+	nodes = append(nodes, &SyntaxNode{Kind: SyntaxToken, Token: token.Type, Range: tokenizer.Range{Start: b.cursor, End: token.End}, Text: b.document[b.cursor:token.End]})
+	b.cursor = token.End
+	return nodes
+}
+
+func (b *syntaxBuilder) nodes(list tokenizer.TokenList[djot_tokenizer.DjotToken]) []*SyntaxNode {
+	nodes := make([]*SyntaxNode, 0)
+	for i := 0; i < len(list); i++ {
+		token := list[i]
+		if token.JumpToPair <= 0 {
+			nodes = b.leaves(nodes, token)
+			continue
+		}
+		if trivia := b.trivia(token.Start); trivia != nil {
+			nodes = append(nodes, trivia)
+		}
+		element := &SyntaxNode{Kind: SyntaxElement, Token: token.Type, Range: tokenizer.Range{Start: b.cursor}}
+		element.Children = b.leaves(nil, token)
+		element.Children = append(element.Children, b.nodes(list[i+1:i+token.JumpToPair])...)
+		element.Children = b.leaves(element.Children, list[i+token.JumpToPair])
+		element.Range.End = b.cursor
+		nodes = append(nodes, element)
+		i += token.JumpToPair
+	}
+	return nodes
+}
+
+// Bytes returns source of the tree with all edits applied
+func (t *SyntaxTree) Bytes() []byte { return t.Root.Bytes() }
+
+// Bytes returns source of the node with all edits applied
+func (n *SyntaxNode) Bytes() []byte {
+	var buffer bytes.Buffer
+	n.write(&buffer)
+	return buffer.Bytes()
+}
+
+func (n *SyntaxNode) write(buffer *bytes.Buffer) {
+	buffer.Write(n.Text)
+	for _, child := range n.Children {
+		child.write(buffer)
+	}
+}
+
+// Elements iterates over the elements of the given type in the document order (node itself included)
+func (n *SyntaxNode) Elements(token djot_tokenizer.DjotToken) iter.Seq[*SyntaxNode] {
+	return func(yield func(*SyntaxNode) bool) { n.elements(token, yield) }
+}
+
+func (n *SyntaxNode) elements(token djot_tokenizer.DjotToken, yield func(*SyntaxNode) bool) bool {
+	if n.Kind == SyntaxElement && n.Token == token && !yield(n) {
+		return false
+	}
+	for _, child := range n.Children {
+		if !child.elements(token, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// content returns bounds of the element children between open and close markers
+func (n *SyntaxNode) content() (int, int) {
+	start, end := 0, len(n.Children)
+	if start < end && n.Children[start].Kind == SyntaxToken && n.Children[start].Token == n.Token {
+		start++
+	}
+	if start < end && n.Children[end-1].Kind == SyntaxToken && n.Children[end-1].Token == n.Token^1 {
+		end--
+	}
+	return start, end
+}
+
+// replace replaces children[start:end] of the element with the single leaf holding text
+func (n *SyntaxNode) replace(start, end int, text []byte) {
+	leaf := &SyntaxNode{Kind: SyntaxToken, Token: djot_tokenizer.None, Text: text}
+	if start < end {
+		leaf.Range = tokenizer.Range{Start: n.Children[start].Range.Start, End: n.Children[end-1].Range.End}
+	} else if start > 0 {
+		leaf.Range = tokenizer.Range{Start: n.Children[start-1].Range.End, End: n.Children[start-1].Range.End}
+	}
+	children := make([]*SyntaxNode, 0, len(n.Children)-(end-start)+1)
+	children = append(children, n.Children[:start]...)
+	children = append(children, leaf)
+	n.Children = append(children, n.Children[end:]...)
+}
+
+// label returns normalized text of the element content
+func (n *SyntaxNode) label() string {
+	start, end := n.content()
+	var buffer bytes.Buffer
+	for _, child := range n.Children[start:end] {
+		child.write(&buffer)
+	}
+	return string(normalizeLinkText(buffer.Bytes()))
+}
+
+// RenameReference changes label of the reference definition and all references to it ([text][from], [from][] and image references);
+// link text of the collapsed references is kept as is. Returns number of the changed places
+func (t *SyntaxTree) RenameReference(from, to string) int {
+	changed := 0
+	for definition := range t.Root.Elements(djot_tokenizer.ReferenceDefBlock) {
+		if marker := definition.Children[0]; marker.Token == djot_tokenizer.ReferenceDefBlock {
+			label := bytes.TrimSuffix(bytes.TrimPrefix(marker.Text, []byte("[")), []byte("]:"))
+			if string(normalizeLinkText(label)) == from {
+				marker.Text = []byte("[" + to + "]:")
+				changed++
+			}
+		}
+	}
+	var rename func(node *SyntaxNode)
+	rename = func(node *SyntaxNode) {
+		for i, child := range node.Children {
+			if child.Kind != SyntaxElement {
+				continue
+			}
+			if child.Token != djot_tokenizer.LinkReferenceInline {
+				rename(child)
+				continue
+			}
+			start, end := child.content()
+			if start < end && child.label() == from {
+				child.replace(start, end, []byte(to))
+				changed++
+			} else if start == end && i > 0 && node.Children[i-1].Kind == SyntaxElement && node.Children[i-1].label() == from {
+				child.replace(start, end, []byte(to))
+				changed++
+			}
+		}
+	}
+	rename(t.Root)
+	return changed
+}
+
+// SetHeadingText replaces inline content of the heading (HeadingBlock element) with text (djot markup is inserted as is);
+// heading marker, trailing attributes and line break are kept
+func (t *SyntaxTree) SetHeadingText(heading *SyntaxNode, text string) {
+	start, end := heading.content()
+	for end > start {
+		last := heading.Children[end-1]
+		if last.Kind == SyntaxElement || last.Token != djot_tokenizer.Attribute && len(bytes.TrimSpace(last.Text)) > 0 {
+			break
+		}
+		end--
+	}
+	heading.replace(start, end, []byte(text))
+}
+
+// ToggleTask flips checkbox of the task list item (ListItemBlock element) and returns its new state (ok is false if item is not a task)
+func (t *SyntaxTree) ToggleTask(item *SyntaxNode) (checked bool, ok bool) {
+	if item.Kind != SyntaxElement || item.Token != djot_tokenizer.ListItemBlock || len(item.Children) == 0 {
+		return false, false
+	}
+	marker := item.Children[0]
+	open := bytes.IndexByte(marker.Text, '[')
+	if marker.Token != djot_tokenizer.ListItemBlock || open == -1 || open+2 >= len(marker.Text) || marker.Text[open+2] != ']' {
+		return false, false
+	}
+	state := marker.Text[open+1]
+	if state != ' ' && state != 'x' && state != 'X' {
+		return false, false
+	}
+	text := bytes.Clone(marker.Text)
+	if state == ' ' {
+		text[open+1] = 'x'
+	} else {
+		text[open+1] = ' '
+	}
+	marker.Text = text
+	return state == ' ', true
+}
@@ -0,0 +1,85 @@
+package djot_parser
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+)
+
+func requireLossless(t *testing.T, node *SyntaxNode, document []byte) {
+	if node.Kind != SyntaxElement {
+		require.Equal(t, string(document[node.Range.Start:node.Range.End]), string(node.Text))
+		return
+	}
+	require.Equal(t, string(document[node.Range.Start:node.Range.End]), string(node.Bytes()))
+	for _, child := range node.Children {
+		requireLossless(t, child, document)
+	}
+}
+
+func TestSyntaxTreeLossless(t *testing.T) {
+	dir, err := os.ReadDir(examplesDir)
+	require.Nil(t, err)
+	for _, entry := range dir {
+		if !strings.HasSuffix(entry.Name(), ".djot") {
+			continue
+		}
+		t.Run(entry.Name(), func(t *testing.T) {
+			document, err := os.ReadFile(path.Join(examplesDir, entry.Name()))
+			require.Nil(t, err)
+			tree := BuildSyntaxTree(document)
+			require.Equal(t, string(document), string(tree.Bytes()))
+			requireLossless(t, tree.Root, document)
+		})
+	}
+	for _, document := range incrementalSnippets {
+		require.Equal(t, document, string(BuildSyntaxTree([]byte(document)).Bytes()))
+	}
+}
+
+func TestSyntaxTreeEdits(t *testing.T) {
+	document := `{% comment %}
+## Old  *title* {#intro}
+
+3. [link][old] and [old][]
+7. ![image][old]
+
+- [ ] first
+- [x] second
+
+[old]: https://example.com
+  {.external}
+`
+	t.Run("rename reference", func(t *testing.T) {
+		tree := BuildSyntaxTree([]byte(document))
+		require.Equal(t, 4, tree.RenameReference("old", "new"))
+		require.Equal(t, strings.NewReplacer("[old]:", "[new]:", "[link][old]", "[link][new]", "[old][]", "[old][new]", "[image][old]", "[image][new]").Replace(document), string(tree.Bytes()))
+		require.Equal(t, 0, tree.RenameReference("missing", "new"))
+	})
+	t.Run("heading", func(t *testing.T) {
+		tree := BuildSyntaxTree([]byte(document))
+		var headings []*SyntaxNode
+		for heading := range tree.Root.Elements(djot_tokenizer.HeadingBlock) {
+			headings = append(headings, heading)
+		}
+		require.Len(t, headings, 1)
+		tree.SetHeadingText(headings[0], "New _title_")
+		require.Equal(t, strings.Replace(document, "Old  *title*", "New _title_", 1), string(tree.Bytes()))
+	})
+	t.Run("toggle task", func(t *testing.T) {
+		tree := BuildSyntaxTree([]byte(document))
+		var states []bool
+		for item := range tree.Root.Elements(djot_tokenizer.ListItemBlock) {
+			if checked, ok := tree.ToggleTask(item); ok {
+				states = append(states, checked)
+			}
+		}
+		require.Equal(t, []bool{true, false}, states)
+		require.Equal(t, strings.NewReplacer("- [ ] first", "- [x] first", "- [x] second", "- [ ] second").Replace(document), string(tree.Bytes()))
+	})
+}
@@ -0,0 +1,122 @@
+package djot_parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sivukhin/godjot/v2/djot_tokenizer"
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// Task is the task list item of the document
+type Task struct {
+	// Text is the plain text of the first paragraph of the item (line breaks are replaced with spaces)
+	Text    string
+	Checked bool
+	// Depth is the number of the list items which contain the task (0 for the top-level list)
+	Depth int
+	// Heading is the plain text of the enclosing section heading (empty if task precedes all headings)
+	Heading string
+	// Range is the source range of the whole item and Checkbox is the source range of the "[ ]" or "[x]" marker
+	Range    tokenizer.Range
+	Checkbox tokenizer.Range
+}
+
+func Tasks(document []byte) []Task { return defaultParser.Tasks(document) }
+
+// Tasks returns all task list items of the document in the document order
+func (p *Parser) Tasks(document []byte) []Task {
+	options := p.options
+	options.SourcePositions = true
+	parsed := (&Parser{options: options, tokenizerOptions: p.tokenizerOptions}).Parse(document)
+	collector := taskCollector{document: document}
+	for _, node := range parsed.Nodes {
+		collector.collect(node, 0)
+	}
+	return collector.tasks
+}
+
+type taskCollector struct {
+	document []byte
+	heading  string
+	tasks    []Task
+}
+
+func (c *taskCollector) collect(node TreeNode[DjotNode], depth int) {
+	switch node.Type {
+	case HeadingNode:
+		c.heading = plainText(node.FullText())
+		return
+	case ListItemNode:
+		class := node.Attributes.Get(djot_tokenizer.DjotAttributeClassKey)
+		if class == CheckedTaskItemClass || class == UncheckedTaskItemClass {
+			c.tasks = append(c.tasks, Task{
+				Text:     plainText(taskText(node)),
+				Checked:  class == CheckedTaskItemClass,
+				Depth:    depth,
+				Heading:  c.heading,
+				Range:    node.Source,
+				Checkbox: taskCheckbox(c.document, node.Source),
+			})
+		}
+		depth++
+	}
+	for _, child := range node.Children {
+		c.collect(child, depth)
+	}
+}
+
+// taskText returns text of the item first paragraph (children of the tight list item are inline nodes)
+func taskText(item TreeNode[DjotNode]) []byte {
+	var text []byte
+	for _, child := range item.Children {
+		if child.Type == ParagraphNode {
+			if len(text) == 0 {
+				return child.FullText()
+			}
+			break
+		}
+		if child.Type.IsList() {
+			break
+		}
+		text = append(text, child.FullText()...)
+	}
+	return text
+}
+
+func plainText(text []byte) string { return strings.Join(strings.Fields(string(text)), " ") }
+
+// taskCheckbox returns range of the checkbox within the list item marker
+func taskCheckbox(document []byte, item tokenizer.Range) tokenizer.Range {
+	for i := item.Start; i+2 < item.End; i++ {
+		if document[i] == '[' && document[i+2] == ']' {
+			return tokenizer.Range{Start: i, End: i + 3}
+		}
+	}
+	return tokenizer.Range{Start: item.Start, End: item.Start}
+}
+
+// ToggleTask flips checkbox of the task by patching document in place with SyntaxTree.ToggleTask edit (checkbox has the same length
+// in both states, so ranges of other tasks stay valid). Returns error if document doesn't have the task checkbox at the expected position
+func ToggleTask(document []byte, task Task) error {
+	checkbox := task.Checkbox
+	if checkbox.Start < 0 || checkbox.End > len(document) || checkbox.End-checkbox.Start != 3 {
+		return fmt.Errorf("task checkbox %v:%v is out of document bounds", checkbox.Start, checkbox.End)
+	}
+	tree := BuildSyntaxTree(document)
+	for item := range tree.Root.Elements(djot_tokenizer.ListItemBlock) {
+		if len(item.Children) == 0 {
+			continue
+		}
+		marker := item.Children[0]
+		if marker.Range.Start > checkbox.Start || checkbox.End > marker.Range.End {
+			continue
+		}
+		if checked, ok := tree.ToggleTask(item); ok && checked != task.Checked {
+			copy(document[marker.Range.Start:marker.Range.End], marker.Text)
+			return nil
+		}
+		break
+	}
+	return fmt.Errorf("document has %q instead of the task checkbox at %v:%v", document[checkbox.Start:checkbox.End], checkbox.Start, checkbox.End)
+}
@@ -0,0 +1,50 @@
+package djot_parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+func TestTasks(t *testing.T) {
+	document := []byte(`- [ ] before headings
+
+# Groceries
+
+- [x] milk
+- [ ] bread *and*
+  butter
+
+  - [ ] rye
+
+## Sub
+
+1. item
+
+   - [X] nested
+`)
+	tasks := Tasks(document)
+	require.Equal(t, []Task{
+		{Text: "before headings", Depth: 0, Range: tokenizer.Range{Start: 0, End: 21}, Checkbox: tokenizer.Range{Start: 2, End: 5}},
+		{Text: "milk", Checked: true, Heading: "Groceries", Range: tokenizer.Range{Start: 36, End: 46}, Checkbox: tokenizer.Range{Start: 38, End: 41}},
+		{Text: "bread and butter", Heading: "Groceries", Range: tokenizer.Range{Start: 47, End: 86}, Checkbox: tokenizer.Range{Start: 49, End: 52}},
+		{Text: "rye", Depth: 1, Heading: "Groceries", Range: tokenizer.Range{Start: 77, End: 86}, Checkbox: tokenizer.Range{Start: 79, End: 82}},
+		{Text: "nested", Checked: true, Depth: 1, Heading: "Sub", Range: tokenizer.Range{Start: 108, End: 120}, Checkbox: tokenizer.Range{Start: 110, End: 113}},
+	}, tasks)
+
+	require.Nil(t, ToggleTask(document, tasks[1]))
+	require.Nil(t, ToggleTask(document, tasks[2]))
+	toggled := Tasks(document)
+	require.False(t, toggled[1].Checked)
+	require.True(t, toggled[2].Checked)
+	require.Equal(t, tasks[2].Range, toggled[2].Range)
+	require.Equal(t, "- [ ] milk", string(document[toggled[1].Range.Start:toggled[1].Range.End]))
+
+	// task is stale after the toggle
+	require.NotNil(t, ToggleTask(document, tasks[1]))
+	require.NotNil(t, ToggleTask(document, Task{Checkbox: tokenizer.Range{Start: 0, End: 100000}}))
+	// checkbox-like text outside of the list item marker
+	require.NotNil(t, ToggleTask([]byte("a [ ] b"), Task{Checkbox: tokenizer.Range{Start: 2, End: 5}}))
+}
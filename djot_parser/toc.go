@@ -0,0 +1,55 @@
+package djot_parser
+
+// TocEntry is the item of the document table of contents
+type TocEntry struct {
+	Level    int
+	Id       string
+	Title    string
+	Children []TocEntry
+}
+
+// TableOfContents builds headings hierarchy of the document based on the heading levels
+func TableOfContents(nodes []TreeNode[DjotNode]) []TocEntry {
+	var headings []TocEntry
+	var collect func(nodes []TreeNode[DjotNode])
+	collect = func(nodes []TreeNode[DjotNode]) {
+		for _, node := range nodes {
+			if node.Type == SectionNode && len(node.Children) > 0 && node.Children[0].Type == HeadingNode {
+				heading := node.Children[0]
+				id := node.Attributes.Get(IdKey)
+				if headingId, ok := heading.Attributes.TryGet(IdKey); ok {
+					id = headingId
+				}
+				headings = append(headings, TocEntry{
+					Level: len(heading.Attributes.Get(HeadingLevelKey)),
+					Id:    id,
+					Title: string(heading.FullText()),
+				})
+				collect(node.Children[1:])
+			} else if node.Type == HeadingNode {
+				// headings are not wrapped into sections if Options.DisableSections is set
+				headings = append(headings, TocEntry{
+					Level: len(node.Attributes.Get(HeadingLevelKey)),
+					Id:    node.Attributes.Get(IdKey),
+					Title: string(node.FullText()),
+				})
+			} else if node.Type == DocumentNode || node.Type == SectionNode {
+				collect(node.Children)
+			}
+		}
+	}
+	collect(nodes)
+
+	var nest func(level int) []TocEntry
+	nest = func(level int) []TocEntry {
+		var entries []TocEntry
+		for len(headings) > 0 && headings[0].Level > level {
+			entry := headings[0]
+			headings = headings[1:]
+			entry.Children = nest(entry.Level)
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	return nest(0)
+}
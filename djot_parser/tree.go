@@ -1,12 +1,18 @@
 package djot_parser
 
-import "github.com/sivukhin/godjot/v2/tokenizer"
+import (
+	"iter"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
 
 type TreeNode[T ~int] struct {
 	Type       T
 	Attributes tokenizer.Attributes
 	Children   []TreeNode[T]
 	Text       []byte
+	// Source is the byte range of the block-level node in the document (filled only with Options.SourcePositions)
+	Source tokenizer.Range
 }
 
 func (n TreeNode[T]) Traverse(f func(node TreeNode[T])) {
@@ -16,6 +22,37 @@ func (n TreeNode[T]) Traverse(f func(node TreeNode[T])) {
 	}
 }
 
+// Descendants iterates over the node and all its descendants in the depth-first order together with their depth
+// (depth of the node itself is 0)
+func (n TreeNode[T]) Descendants() iter.Seq2[int, TreeNode[T]] {
+	return func(yield func(int, TreeNode[T]) bool) {
+		n.descendants(0, yield)
+	}
+}
+
+func (n TreeNode[T]) descendants(depth int, yield func(int, TreeNode[T]) bool) bool {
+	if !yield(depth, n) {
+		return false
+	}
+	for _, child := range n.Children {
+		if !child.descendants(depth+1, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// DescendantsOfType iterates over the node and all its descendants of the given type in the depth-first order
+func (n TreeNode[T]) DescendantsOfType(nodeType T) iter.Seq[TreeNode[T]] {
+	return func(yield func(TreeNode[T]) bool) {
+		for _, node := range n.Descendants() {
+			if node.Type == nodeType && !yield(node) {
+				return
+			}
+		}
+	}
+}
+
 func (n TreeNode[T]) FullText() []byte {
 	textNodes := 0
 	var text []byte
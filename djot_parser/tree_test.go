@@ -0,0 +1,48 @@
+package djot_parser
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeIterators(t *testing.T) {
+	document := []byte("# Title\n\n*a* _b_ *c*\n\n> *d*")
+	root := NewParser(Options{DisableSections: true}).Parse(document).Nodes[0]
+	t.Run("descendants", func(t *testing.T) {
+		var depths []int
+		var types []DjotNode
+		for depth, node := range root.Descendants() {
+			if depth > 2 {
+				continue
+			}
+			depths = append(depths, depth)
+			types = append(types, node.Type)
+		}
+		require.Equal(t, []int{0, 1, 2, 1, 2, 2, 2, 2, 2, 1, 2}, depths)
+		require.Equal(t, []DjotNode{
+			DocumentNode, HeadingNode, TextNode, ParagraphNode, StrongNode, TextNode, EmphasisNode, TextNode, StrongNode, QuoteNode, ParagraphNode,
+		}, types)
+	})
+	t.Run("descendants of type", func(t *testing.T) {
+		var texts []string
+		for node := range root.DescendantsOfType(StrongNode) {
+			texts = append(texts, string(node.FullText()))
+			if len(texts) == 2 {
+				break
+			}
+		}
+		require.Equal(t, []string{"a", "c"}, texts)
+	})
+	t.Run("blocks", func(t *testing.T) {
+		parser := NewParser(Options{})
+		document := []byte("# One\n\ntext[^1]\n\n# Two\n\n[^1]: note")
+		blocks := slices.Collect(parser.Blocks(document))
+		require.Equal(t, parser.Parse(document).Nodes[0].Children, blocks)
+		for block := range parser.Blocks(document) {
+			require.Equal(t, "One", block.Attributes.Get(IdKey))
+			break
+		}
+	})
+}
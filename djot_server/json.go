@@ -0,0 +1,55 @@
+package djot_server
+
+import (
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+// jsonNode is the JSON representation of the AST node: attributes are listed in the source order and text is set only for text nodes
+type jsonNode struct {
+	Type       djot_parser.DjotNode `json:"type"`
+	Attributes [][2]string          `json:"attributes,omitempty"`
+	Children   []jsonNode           `json:"children,omitempty"`
+	Text       *string              `json:"text,omitempty"`
+}
+
+type jsonDiagnostic struct {
+	Severity string `json:"severity"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Message  string `json:"message"`
+}
+
+type jsonDocument struct {
+	Nodes       []jsonNode           `json:"nodes"`
+	Metadata    djot_parser.Metadata `json:"metadata,omitempty"`
+	Diagnostics []jsonDiagnostic     `json:"diagnostics"`
+}
+
+func newJsonDocument(document djot_parser.DjotDocument) jsonDocument {
+	diagnostics := make([]jsonDiagnostic, 0, len(document.Diagnostics))
+	for _, diagnostic := range document.Diagnostics {
+		diagnostics = append(diagnostics, jsonDiagnostic{
+			Severity: diagnostic.Severity.String(),
+			Start:    diagnostic.Range.Start,
+			End:      diagnostic.Range.End,
+			Message:  diagnostic.Message,
+		})
+	}
+	return jsonDocument{Nodes: newJsonNodes(document.Nodes), Metadata: document.Metadata, Diagnostics: diagnostics}
+}
+
+func newJsonNodes(nodes []djot_parser.TreeNode[djot_parser.DjotNode]) []jsonNode {
+	result := make([]jsonNode, 0, len(nodes))
+	for _, node := range nodes {
+		converted := jsonNode{Type: node.Type, Children: newJsonNodes(node.Children)}
+		for _, entry := range node.Attributes.Entries() {
+			converted.Attributes = append(converted.Attributes, [2]string{entry.Key, entry.Value})
+		}
+		if node.Type == djot_parser.TextNode {
+			text := string(node.Text)
+			converted.Text = &text
+		}
+		result = append(result, converted)
+	}
+	return result
+}
@@ -0,0 +1,195 @@
+package djot_server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/sivukhin/godjot/v2/djot_html"
+	"github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/djot_text"
+)
+
+// Config configures rendering server (zero value of the field means default value)
+type Config struct {
+	// Parser configures parsing of the documents; Parser.Limits are enforced for every request
+	Parser djot_parser.Options
+	// Safe enables safe rendering mode of html output (see djot_html.Safe)
+	Safe bool
+	// MaxBodySize is the maximum size of the request body in bytes (1 MiB by default)
+	MaxBodySize int64
+	// MaxConcurrent is the maximum number of simultaneously rendered requests (number of CPUs by default);
+	// other requests wait for the free slot until timeout
+	MaxConcurrent int
+	// Timeout bounds processing time of the request including waiting for the free slot (10 seconds by default)
+	Timeout time.Duration
+}
+
+const (
+	defaultMaxBodySize = 1 << 20
+	defaultTimeout     = 10 * time.Second
+)
+
+// Server renders djot documents over HTTP:
+//   - POST /render?to=html|text|json renders request body (raw djot or JSON object {"djot": "..."}) to the requested format (html by default)
+//   - GET /healthz responds with 200 status
+type Server struct {
+	config Config
+	parser *djot_parser.Parser
+	html   djot_parser.ConversionContext[*djot_html.HtmlWriter]
+	text   djot_parser.ConversionContext[*djot_text.TextWriter]
+	slots  chan struct{}
+	mux    *http.ServeMux
+}
+
+func NewServer(config Config) *Server {
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxBodySize
+	}
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = runtime.NumCPU()
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	s := &Server{
+		config: config,
+		parser: djot_parser.NewParser(config.Parser),
+		html:   djot_html.New(),
+		text:   djot_text.New(),
+		slots:  make(chan struct{}, config.MaxConcurrent),
+		mux:    http.NewServeMux(),
+	}
+	if config.Safe {
+		s.html = djot_html.Safe(s.html)
+	}
+	s.mux.HandleFunc("/render", s.render)
+	s.mux.HandleFunc("/healthz", s.healthz)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) { s.mux.ServeHTTP(w, r) }
+
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method %v is not allowed", r.Method)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "ok\n")
+}
+
+// renderRequest is the JSON body of the render request
+type renderRequest struct {
+	Djot string `json:"djot"`
+}
+
+func (s *Server) render(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method %v is not allowed", r.Method)
+		return
+	}
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = "html"
+	}
+	if to != "html" && to != "text" && to != "json" {
+		writeError(w, http.StatusBadRequest, "unsupported output format %q (expected html, text or json)", to)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.Timeout)
+	defer cancel()
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.config.MaxBodySize))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body is too large (limit is %v bytes)", s.config.MaxBodySize)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, "failed to read request body: %v", err)
+		return
+	}
+	input := body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		var request renderRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: %v", err)
+			return
+		}
+		input = []byte(request.Djot)
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		writeError(w, http.StatusServiceUnavailable, "server is busy")
+		return
+	}
+	document, err := s.parser.ParseContext(ctx, input)
+	switch {
+	case errors.Is(err, djot_parser.ErrInputTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "%v", err)
+		return
+	case errors.Is(err, djot_parser.ErrTooManyNodes):
+		writeError(w, http.StatusUnprocessableEntity, "%v", err)
+		return
+	case err != nil:
+		writeContextError(w, err)
+		return
+	}
+
+	// conversion can't be interrupted, so the deadline is checked once output is built and nothing is written if it's exceeded
+	var (
+		contentType string
+		output      string
+		value       any
+	)
+	switch to {
+	case "html":
+		contentType, output = "text/html; charset=utf-8", s.html.ConvertDjotDocument(&djot_html.HtmlWriter{}, document).String()
+	case "text":
+		contentType, output = "text/plain; charset=utf-8", s.text.ConvertDjotDocument(&djot_text.TextWriter{}, document).String()
+	case "json":
+		value = newJsonDocument(document)
+	}
+	if err := ctx.Err(); err != nil {
+		writeContextError(w, err)
+		return
+	}
+	if value != nil {
+		writeJson(w, http.StatusOK, value)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = io.WriteString(w, output)
+}
+
+func writeContextError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeError(w, http.StatusServiceUnavailable, "rendering timed out")
+		return
+	}
+	// client has gone away, so the status is never delivered
+	writeError(w, http.StatusServiceUnavailable, "%v", err)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJson(w, status, errorResponse{Error: fmt.Sprintf(format, args...)})
+}
+
+func writeJson(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
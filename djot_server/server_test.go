@@ -0,0 +1,107 @@
+package djot_server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/djot_html"
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func post(server *Server, target, contentType, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServerRender(t *testing.T) {
+	server := NewServer(Config{})
+	t.Run("html", func(t *testing.T) {
+		response := post(server, "/render", "text/plain", "*Hello*, _world_")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, "text/html; charset=utf-8", response.Header().Get("Content-Type"))
+		require.Equal(t, "<p><strong>Hello</strong>, <em>world</em></p>\n", response.Body.String())
+	})
+	t.Run("text", func(t *testing.T) {
+		response := post(server, "/render?to=text", "", "*Hello*, _world_")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, "Hello, world\n", response.Body.String())
+	})
+	t.Run("json body", func(t *testing.T) {
+		response := post(server, "/render?to=json", "application/json; charset=utf-8", `{"djot": "{.x}\n_a_ [b][]"}`)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, `{"nodes":[{"type":"DocumentNode","children":[{"type":"ParagraphNode","attributes":[["class","x"]],"children":[{"type":"EmphasisNode","children":[{"type":"TextNode","text":"a"}]},{"type":"TextNode","text":" "},{"type":"LinkNode","children":[{"type":"TextNode","text":"b"}]}]}]}],"diagnostics":[{"severity":"warning","start":9,"end":14,"message":"undefined reference [b]"}]}
+`, response.Body.String())
+	})
+	t.Run("safe", func(t *testing.T) {
+		unsafe := "[link](javascript:void)\n\n``` =html\n<script></script>\n```\n"
+		require.Contains(t, post(server, "/render", "", unsafe).Body.String(), "<script>")
+		response := post(NewServer(Config{Safe: true}), "/render", "", unsafe)
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, "<p><a>link</a></p>\n", response.Body.String())
+	})
+}
+
+func TestServerErrors(t *testing.T) {
+	t.Run("format", func(t *testing.T) {
+		response := post(NewServer(Config{}), "/render?to=pdf", "", "text")
+		require.Equal(t, http.StatusBadRequest, response.Code)
+		require.Equal(t, `{"error":"unsupported output format \"pdf\" (expected html, text or json)"}`+"\n", response.Body.String())
+	})
+	t.Run("invalid json", func(t *testing.T) {
+		response := post(NewServer(Config{}), "/render", "application/json", "text")
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+	t.Run("method", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		NewServer(Config{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/render", nil))
+		require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	})
+	t.Run("body size", func(t *testing.T) {
+		response := post(NewServer(Config{MaxBodySize: 4}), "/render", "", "hello")
+		require.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+	})
+	t.Run("limits", func(t *testing.T) {
+		server := NewServer(Config{Parser: djot_parser.Options{Limits: djot_parser.Limits{MaxInputSize: 4, MaxNodes: 3}}})
+		require.Equal(t, http.StatusRequestEntityTooLarge, post(server, "/render", "", "hello").Code)
+		require.Equal(t, http.StatusUnprocessableEntity, post(server, "/render", "", "_a_").Code)
+		require.Equal(t, http.StatusOK, post(server, "/render", "", "a").Code)
+	})
+	t.Run("busy", func(t *testing.T) {
+		server := NewServer(Config{MaxConcurrent: 1, Timeout: 10 * time.Millisecond})
+		server.slots <- struct{}{}
+		response := post(server, "/render", "", "text")
+		require.Equal(t, http.StatusServiceUnavailable, response.Code)
+		require.Equal(t, `{"error":"server is busy"}`+"\n", response.Body.String())
+		<-server.slots
+		require.Equal(t, http.StatusOK, post(server, "/render", "", "text").Code)
+	})
+	t.Run("render timeout", func(t *testing.T) {
+		server := NewServer(Config{Timeout: 20 * time.Millisecond})
+		server.html = djot_html.New(djot_html.DefaultConversionRegistry, map[djot_parser.DjotNode]djot_parser.Conversion[*djot_html.HtmlWriter]{
+			djot_parser.DocumentNode: func(s djot_parser.ConversionState[*djot_html.HtmlWriter], n func(c djot_parser.Children)) {
+				time.Sleep(50 * time.Millisecond)
+				n(nil)
+			},
+		})
+		response := post(server, "/render", "", "text")
+		require.Equal(t, http.StatusServiceUnavailable, response.Code)
+		require.Equal(t, `{"error":"rendering timed out"}`+"\n", response.Body.String())
+	})
+}
+
+func TestServerHealthz(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewServer(Config{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "ok\n", recorder.Body.String())
+}
@@ -0,0 +1,120 @@
+package djot_text
+
+import (
+	"maps"
+	"strings"
+
+	. "github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+// TextWriter accumulates plain text content of the document
+// Blocks are separated with blank lines and list items / table rows are placed on separate lines
+type TextWriter struct {
+	Builder strings.Builder
+}
+
+func (w *TextWriter) String() string {
+	text := strings.TrimRight(w.Builder.String(), "\n")
+	if text == "" {
+		return ""
+	}
+	return text + "\n"
+}
+
+func (w *TextWriter) WriteString(text string) *TextWriter {
+	w.Builder.WriteString(text)
+	return w
+}
+
+// StartBlock moves writer to the new line (with blank line before the block if separate is true)
+func (w *TextWriter) StartBlock(separate bool) *TextWriter {
+	text := w.Builder.String()
+	if text == "" {
+		return w
+	}
+	if !strings.HasSuffix(text, "\n") {
+		w.Builder.WriteString("\n")
+	}
+	if separate && !strings.HasSuffix(text, "\n\n") {
+		w.Builder.WriteString("\n")
+	}
+	return w
+}
+
+func BlockNodeConverter(state ConversionState[*TextWriter], separate bool, next func(Children)) *TextWriter {
+	state.Writer.StartBlock(separate)
+	next(nil)
+	return state.Writer.StartBlock(false)
+}
+
+var DefaultConversionRegistry = map[DjotNode]Conversion[*TextWriter]{
+	DocumentNode:          func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	SectionNode:           func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	ParagraphNode:         func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	HeadingNode:           func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	QuoteNode:             func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	DivNode:               func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	CodeNode:              func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	TableNode:             func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	TableCaptionNode:      func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, false, n) },
+	TableRowNode:          func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, false, n) },
+	UnorderedListNode:     func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	OrderedListNode:       func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	TaskListNode:          func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	DefinitionListNode:    func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, true, n) },
+	ListItemNode:          func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, false, n) },
+	DefinitionTermNode:    func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, false, n) },
+	DefinitionItemNode:    func(s ConversionState[*TextWriter], n func(Children)) { BlockNodeConverter(s, false, n) },
+	FootnoteDefNode:       func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	FootnoteReferenceNode: func(s ConversionState[*TextWriter], n func(Children)) { n(s.Node.Children[:1]) },
+	ThematicBreakNode:     func(s ConversionState[*TextWriter], n func(Children)) { s.Writer.StartBlock(true) },
+	TableHeaderNode: func(s ConversionState[*TextWriter], n func(Children)) {
+		n(nil)
+		s.Writer.WriteString("\t")
+	},
+	TableCellNode: func(s ConversionState[*TextWriter], n func(Children)) {
+		n(nil)
+		s.Writer.WriteString("\t")
+	},
+	RawNode: func(s ConversionState[*TextWriter], n func(Children)) {
+		if s.Node.Attributes.Get(RawBlockFormatKey) == s.Format {
+			BlockNodeConverter(s, true, n)
+		}
+	},
+	TextNode:      func(s ConversionState[*TextWriter], n func(Children)) { s.Writer.WriteString(string(s.Node.Text)) },
+	LineBreakNode: func(s ConversionState[*TextWriter], n func(Children)) { s.Writer.WriteString("\n") },
+	SymbolsNode: func(s ConversionState[*TextWriter], n func(Children)) {
+		s.Writer.WriteString(":" + string(s.Node.FullText()) + ":")
+	},
+	ImageNode: func(s ConversionState[*TextWriter], n func(Children)) {
+		s.Writer.WriteString(s.Node.Attributes.Get(ImgAltKey))
+	},
+	EmphasisNode:    func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	StrongNode:      func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	HighlightedNode: func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	SubscriptNode:   func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	SuperscriptNode: func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	InsertNode:      func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	DeleteNode:      func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	VerbatimNode: func(s ConversionState[*TextWriter], n func(Children)) {
+		if rawFormat, ok := s.Node.Attributes.TryGet(RawInlineFormatKey); !ok || rawFormat == s.Format {
+			n(nil)
+		}
+	},
+	LinkNode: func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+	SpanNode: func(s ConversionState[*TextWriter], n func(Children)) { n(nil) },
+}
+
+func New(converters ...map[DjotNode]Conversion[*TextWriter]) ConversionContext[*TextWriter] {
+	if len(converters) == 0 {
+		converters = []map[DjotNode]Conversion[*TextWriter]{DefaultConversionRegistry}
+	}
+	registry := make(map[DjotNode]Conversion[*TextWriter])
+	for i := range converters {
+		maps.Copy(registry, converters[i])
+	}
+	return ConversionContext[*TextWriter]{
+		Format:   "text",
+		Registry: registry,
+	}
+}
@@ -0,0 +1,27 @@
+package djot_text
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func TestConvertText(t *testing.T) {
+	for _, tt := range []struct{ djot, text string }{
+		{djot: "", text: ""},
+		{djot: "*Hello*, _world_", text: "Hello, world\n"},
+		{
+			djot: "# Title\n\nParagraph with [link](url) and ![alt](src).\n\n- one\n- two\n\n> quote\n\n``` go\ncode\n```\n\n`raw`{=html}",
+			text: "Title\n\nParagraph with link and alt.\n\none\ntwo\n\nquote\n\ncode\n",
+		},
+		{djot: "| a | b |\n| c | d |", text: "a\tb\t\nc\td\t\n"},
+		{djot: "text[^1]\n\n[^1]: note", text: "text1\n\nnote↩︎︎\n"},
+	} {
+		t.Run(tt.djot, func(t *testing.T) {
+			ast := djot_parser.BuildDjotAst([]byte(tt.djot))
+			require.Equal(t, tt.text, New().ConvertDjot(&TextWriter{}, ast...).String())
+		})
+	}
+}
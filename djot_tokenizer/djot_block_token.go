@@ -193,3 +193,41 @@ func MatchBlockToken(
 		return fail()
 	}
 }
+
+var metadataFences = [...]string{"---", "..."}
+
+// MatchMetadataBlock matches metadata block (front matter) fenced with --- lines at the start of the document
+// On success tokenizer is advanced right after the closing fence (which can be either --- or ...)
+func MatchMetadataBlock(lineTokenizer *tokenizer.LineTokenizer) ([]tokenizer.Token[DjotToken], bool) {
+	scanner := *lineTokenizer
+	isFence := func(start, end int, fences ...string) bool {
+		line := bytes.TrimRight(scanner.Document[start:end], " \t\r\n")
+		for _, fence := range fences {
+			if string(line) == fence {
+				return true
+			}
+		}
+		return false
+	}
+
+	openStart, openEnd, eof := scanner.Scan()
+	if eof || !isFence(openStart, openEnd, metadataFences[0]) {
+		return nil, false
+	}
+	tokens := []tokenizer.Token[DjotToken]{{Type: MetadataBlock, Start: openStart, End: openEnd}}
+	for {
+		lineStart, lineEnd, eof := scanner.Scan()
+		if eof {
+			return nil, false
+		}
+		if isFence(lineStart, lineEnd, metadataFences[:]...) {
+			tokens = append(tokens, tokenizer.Token[DjotToken]{Type: MetadataBlock ^ tokenizer.Open, Start: lineStart, End: lineEnd})
+			break
+		}
+		tokens = append(tokens, tokenizer.Token[DjotToken]{Start: lineStart, End: lineEnd})
+	}
+	tokens[0].JumpToPair = len(tokens) - 1
+	tokens[len(tokens)-1].JumpToPair = -(len(tokens) - 1)
+	*lineTokenizer = scanner
+	return tokens, true
+}
@@ -50,6 +50,7 @@ const (
 	SymbolsInline
 	PipeTableSeparator
 	SmartSymbolInline
+	MetadataBlock
 )
 
 func (t DjotToken) String() string {
@@ -82,6 +83,8 @@ func (t DjotToken) String() string {
 		return "ThematicBreakToken"
 	case PipeTableCaptionBlock:
 		return "PipeTableCaptionBlock"
+	case MetadataBlock:
+		return "MetadataBlock"
 	case Attribute:
 		return "Attribute"
 	case Padding:
@@ -125,6 +128,9 @@ func (t DjotToken) String() string {
 	case PipeTableSeparator:
 		return "PipeTableSeparator"
 	}
+	if name, ok := extensionTokenName(t); ok {
+		return name
+	}
 	if t&1 == 0 {
 		return (t ^ 1).String() + "Close"
 	}
@@ -11,6 +11,14 @@ func BuildInlineDjotTokens(
 	document []byte,
 	parts ...tokenizer.Range,
 ) []tokenizer.Token[DjotToken] {
+	return Options{}.BuildInlineDjotTokens(document, parts...)
+}
+
+func (o Options) BuildInlineDjotTokens(
+	document []byte,
+	parts ...tokenizer.Range,
+) []tokenizer.Token[DjotToken] {
+	inlineStartSymbols, extensions := o.inlineMatchers()
 	if len(parts) == 0 {
 		parts = []tokenizer.Range{{Start: 0, End: len(document)}}
 	}
@@ -27,11 +35,13 @@ func BuildInlineDjotTokens(
 		tokenStack.LastLevel().FillUntil(part.Start, Ignore)
 
 	inlineParsingLoop:
-		for !reader.IsEmpty(state) {
+		for !reader.IsEmpty(state) && !o.interrupted() {
 			openInline := tokenStack.LastLevel().FirstOrDefault()
 			openInlineType := openInline.Type
 
 			lastInline := tokenStack.LastLevel().LastOrDefault()
+			// Levels of the stack are: root level, paragraph level and levels of the open inline elements
+			delimitersLimitReached := o.MaxInlineDelimiters > 0 && len(tokenStack.Levels)-2 >= o.MaxInlineDelimiters
 
 			// Check if verbatim is open - then we can't process any inline-level elements
 			if openInlineType == VerbatimInline {
@@ -54,6 +64,17 @@ func BuildInlineDjotTokens(
 				state = next
 				continue
 			}
+			// Check if verbatim extension is open - it behaves exactly as VerbatimInline but with custom close token
+			if extension := findInlineMatcher(extensions, openInlineType); extension != nil && extension.Verbatim {
+				next, ok := extension.MatchClose(reader, state)
+				if !ok {
+					state++
+					continue
+				}
+				tokenStack.CloseLevelAt(tokenizer.Token[DjotToken]{Type: openInlineType ^ tokenizer.Open, Start: state, End: next})
+				state = next
+				continue
+			}
 
 			// Try match inline attribute
 			if attributes, next, ok := MatchDjotAttribute(reader, state); ok {
@@ -67,7 +88,7 @@ func BuildInlineDjotTokens(
 				continue
 			}
 
-			if !InlineTokenStartSymbol.Has(reader[state]) {
+			if !inlineStartSymbols.Has(reader[state]) {
 				state++
 				continue
 			}
@@ -81,6 +102,30 @@ func BuildInlineDjotTokens(
 				}
 			}
 
+			for _, extension := range extensions {
+				if !extension.startSymbols.Has(reader[state]) {
+					continue
+				}
+				if extension.MatchClose != nil {
+					if next, ok := extension.MatchClose(reader, state); ok && tokenStack.PopForgetUntil(extension.Type) {
+						tokenStack.CloseLevelAt(tokenizer.Token[DjotToken]{Type: extension.Type ^ tokenizer.Open, Start: state, End: next})
+						state = next
+						continue inlineParsingLoop
+					}
+				}
+				next, ok := extension.MatchOpen(reader, state)
+				if !ok || extension.MatchClose != nil && delimitersLimitReached {
+					continue
+				}
+				if extension.MatchClose == nil {
+					tokenStack.LastLevel().Push(tokenizer.Token[DjotToken]{Type: extension.Type, Start: state, End: next})
+				} else {
+					tokenStack.OpenLevelAt(tokenizer.Token[DjotToken]{Type: extension.Type, Start: state, End: next})
+				}
+				state = next
+				continue inlineParsingLoop
+			}
+
 			for _, tokenType := range [...]DjotToken{
 				RawFormatInline,
 				VerbatimInline,
@@ -120,7 +165,7 @@ func BuildInlineDjotTokens(
 					continue
 				}
 				next, ok = MatchInlineToken(reader, state, tokenType)
-				if ok {
+				if ok && !delimitersLimitReached {
 					var attributes tokenizer.Attributes
 					token := reader[state:next]
 					if tokenType == VerbatimInline && bytes.HasPrefix(token, []byte("$$")) {
@@ -153,6 +198,10 @@ func BuildInlineDjotTokens(
 }
 
 func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
+	return Options{}.BuildDjotTokens(document)
+}
+
+func (o Options) BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 	var (
 		lineTokenizer = tokenizer.LineTokenizer{Document: document}
 
@@ -176,13 +225,13 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 		blockTokens = append(blockTokens, token)
 	}
 	closeBlockLevelsUntil := func(start, end, level int) {
-		if len(inlineParts) != 0 && blockTokens[len(blockTokens)-1].Type == CodeBlock {
+		if len(inlineParts) != 0 && o.isVerbatimBlock(blockTokens[len(blockTokens)-1].Type) {
 			for _, inlinePart := range inlineParts {
 				finalTokens = append(finalTokens, tokenizer.Token[DjotToken]{Start: inlinePart.Start, End: inlinePart.End})
 			}
 			inlineParts = nil
 		} else if len(inlineParts) != 0 {
-			finalTokens = append(finalTokens, BuildInlineDjotTokens(document, inlineParts...)...)
+			finalTokens = append(finalTokens, o.BuildInlineDjotTokens(document, inlineParts...)...)
 			inlineParts = nil
 		}
 		for i := len(blockTokens) - 1; i > level; i-- {
@@ -198,7 +247,18 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 		}
 	}
 
-	for {
+	// Metadata block (front matter) can appear only at the very beginning of the document (block extensions take precedence over it)
+	if o.FrontMatter {
+		firstLine := lineTokenizer
+		_, firstLineEnd, _ := firstLine.Scan()
+		if !o.hasBlockExtensionAt(tokenizer.TextReader(document[:firstLineEnd]), 0) {
+			if metadataTokens, ok := MatchMetadataBlock(&lineTokenizer); ok {
+				finalTokens = append(finalTokens, metadataTokens...)
+			}
+		}
+	}
+
+	for !o.interrupted() {
 		lineStart, lineEnd, eof := lineTokenizer.Scan()
 		if eof {
 			break
@@ -208,8 +268,8 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 		lastBlock := blockTokens[len(blockTokens)-1]
 		lastBlockType := lastBlock.Type
 
-		// Try to match block element attribute ({...}) at the start of the line (only in case if last block token was [Document | Quote | ListItem | Div])
-		if lastBlockType == DocumentBlock || lastBlockType == QuoteBlock || lastBlockType == ListItemBlock || lastBlockType == DivBlock {
+		// Try to match block element attribute ({...}) at the start of the line (only in case if last block token was [Document | Quote | ListItem | Div | container extension])
+		if lastBlockType == DocumentBlock || lastBlockType == QuoteBlock || lastBlockType == ListItemBlock || o.isFencedContainer(lastBlockType) {
 			next, ok := reader.MaskRepeat(state, tokenizer.SpaceByteMask, 0)
 			tokenizer.Assertf(ok, "MaskRepeat must match because minCount is zero")
 
@@ -231,12 +291,12 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 		lastDivAt := -1
 		for i := 0; i < len(blockTokens); i++ {
 			blockToken := blockTokens[i]
-			if blockToken.Type == DivBlock {
+			if o.isFencedContainer(blockToken.Type) {
 				lastDivAt = i
 			}
 		}
 		// Skip optional padding for Heading & Quotes (#, > padding) and remember last matched block token
-		resetBlockAt, potentialReset := 0, false
+		resetBlockAt, potentialReset, footnoteReset, continuationReset := 0, false, false, false
 		for i := 0; i < len(blockTokens); i++ {
 			blockToken := blockTokens[i]
 			if blockToken.Type == ListItemBlock || blockToken.Type == FootnoteDefBlock {
@@ -244,7 +304,7 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 				tokenizer.Assertf(ok, "MaskRepeat must match because minCount is zero")
 
 				if !reader.IsEmptyOrWhiteSpace(next) && next-lineStart <= blockLineOffset[i] {
-					potentialReset = true
+					potentialReset, footnoteReset = true, blockToken.Type == FootnoteDefBlock
 					break
 				}
 				resetBlockAt = i
@@ -264,12 +324,21 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 				}
 				state = next
 				resetBlockAt = i
+			} else if extension := o.findBlockExtension(blockToken.Type); extension != nil && extension.MatchContinue != nil {
+				next, ok := extension.MatchContinue(reader, state, blockToken)
+				if !ok {
+					potentialReset, continuationReset = true, true
+					break
+				}
+				state = next
+				resetBlockAt = i
 			} else if blockToken.Type != ParagraphBlock && blockToken.Type != HeadingBlock && blockToken.Type != PipeTableCaptionBlock {
 				resetBlockAt = i
 			}
 		}
 
-		if (lastBlockType != CodeBlock || potentialReset) && reader.IsEmptyOrWhiteSpace(state) {
+		lastBlockVerbatim := o.isVerbatimBlock(lastBlockType)
+		if (!lastBlockVerbatim || potentialReset) && reader.IsEmptyOrWhiteSpace(state) {
 			closeBlockLevelsUntil(state, state, resetBlockAt)
 			continue
 		}
@@ -278,8 +347,27 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 		if lastBlockType == ReferenceDefBlock {
 			closeBlockLevelsUntil(state, state, resetBlockAt)
 		}
+		// Not indented line closes FootnoteDefBlock unless it is a lazy continuation of the paragraph
+		// Line which doesn't match continuation rule of the block extension closes it unconditionally
+		if footnoteReset && lastBlockType != ParagraphBlock && !lastBlockVerbatim || continuationReset {
+			closeBlockLevelsUntil(state, state, resetBlockAt)
+			lastBlock = blockTokens[len(blockTokens)-1]
+			lastBlockType = lastBlock.Type
+			lastBlockVerbatim = o.isVerbatimBlock(lastBlockType)
+			for lastDivAt >= len(blockTokens) || lastDivAt != -1 && !o.isFencedContainer(blockTokens[lastDivAt].Type) {
+				lastDivAt--
+			}
+		}
 
-		// Check if last block is CodeBlock - then any block level logic should be disabled until we close this block
+		// Check if last block is CodeBlock (or verbatim extension) - then any block level logic should be disabled until we close this block
+		if lastBlockVerbatim && lastBlockType != CodeBlock {
+			if token, ok := o.matchBlockExtensionClose(reader, state, lastBlock); ok {
+				closeBlockLevelsUntil(token.Start, token.End, len(blockTokens)-2)
+			} else {
+				inlineParts = append(inlineParts, tokenizer.Range{Start: state, End: lineEnd})
+			}
+			continue
+		}
 		if lastBlockType == CodeBlock {
 			token, _, ok := MatchBlockToken(reader, state, CodeBlock)
 			if ok && lastBlock.PrefixLength(document, '`') <= token.PrefixLength(document, '`') && token.Attributes.Size() == 0 {
@@ -290,8 +378,13 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 			continue
 		}
 
-		// Check if we can close DivBlock
-		if lastDivAt != -1 {
+		// Check if we can close DivBlock (or container extension)
+		if lastDivAt != -1 && blockTokens[lastDivAt].Type != DivBlock {
+			if token, ok := o.matchBlockExtensionClose(reader, state, blockTokens[lastDivAt]); ok {
+				closeBlockLevelsUntil(token.Start, token.End, lastDivAt-1)
+				continue
+			}
+		} else if lastDivAt != -1 {
 			token, _, ok := MatchBlockToken(reader, state, DivBlock)
 			if ok && lastBlock.Length() <= token.Length() && token.Attributes.Size() == 0 {
 				closeBlockLevelsUntil(token.Start, token.End, lastDivAt-1)
@@ -304,9 +397,11 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 		for {
 			lastBlock = blockTokens[len(blockTokens)-1]
 			lastBlockType = lastBlock.Type
+			// Only paragraph can be opened when nesting limit is reached - so the rest of the line becomes its content
+			depthLimitReached := o.MaxNestingDepth > 0 && len(blockTokens)-1 >= o.MaxNestingDepth
 
-			// Check if thematic break finishes the line
-			if thematicBreak, next, ok := MatchBlockToken(reader, state, ThematicBreakToken); ok {
+			// Check if thematic break finishes the line (block extensions can reuse thematic break symbols for their fences)
+			if thematicBreak, next, ok := MatchBlockToken(reader, state, ThematicBreakToken); ok && !o.hasBlockExtensionAt(reader, state) {
 				finalTokens = append(finalTokens, tokenizer.Token[DjotToken]{
 					Type:  ThematicBreakToken,
 					Start: thematicBreak.Start,
@@ -331,12 +426,13 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 
 			// Heading & CodeBlock can't have nested block level content
 			// Paragraph too - but there are subtle rules for list item handling, so we can't break for paragraphs here
-			if listItem, next, ok := MatchBlockToken(reader, state, ListItemBlock); ok && lastBlockType != HeadingBlock && lastBlockType != CodeBlock {
+			lastBlockVerbatim = o.isVerbatimBlock(lastBlockType)
+			if listItem, next, ok := MatchBlockToken(reader, state, ListItemBlock); ok && lastBlockType != HeadingBlock && !lastBlockVerbatim && (!depthLimitReached || resetListPosition != -1) {
 				if resetListPosition != -1 {
 					closeBlockLevelsUntil(state, state, resetListPosition-1)
 				}
 				// If we found list item which fits some previously defined hierarchy - then we will add it unconditionally
-				if resetListPosition != -1 || lastBlockType != ParagraphBlock && lastBlockType != HeadingBlock && lastBlockType != CodeBlock {
+				if resetListPosition != -1 || lastBlockType != ParagraphBlock && lastBlockType != HeadingBlock && !lastBlockVerbatim {
 					openBlockLevel(tokenizer.Token[DjotToken]{Type: ListItemBlock, Start: listItem.Start, End: listItem.End})
 					blockLineOffset = append(blockLineOffset, listItem.Start-lineStart)
 					state = next
@@ -354,7 +450,11 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 				break blockParsingLoop
 			}
 
-			if lastBlockType == CodeBlock {
+			if lastBlockVerbatim {
+				// Content of the verbatim extension with continuation rule starts right after the open token
+				if extension := o.findBlockExtension(lastBlockType); extension != nil && extension.MatchContinue != nil && !reader.IsEmpty(state) {
+					inlineParts = append(inlineParts, tokenizer.Range{Start: state, End: lineEnd})
+				}
 				break blockParsingLoop
 			}
 
@@ -363,6 +463,14 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 				continue blockParsingLoop
 			}
 
+			// Block extensions take precedence over all built-in block elements
+			if block, next, ok := o.matchBlockExtension(reader, state); ok && !depthLimitReached {
+				openBlockLevel(block)
+				blockLineOffset = append(blockLineOffset, block.Start-lineStart)
+				state = next
+				continue blockParsingLoop
+			}
+
 			// Handle all other block elements - ParagraphBlock must be last item in the sequence
 			for _, tokenType := range [...]DjotToken{
 				FootnoteDefBlock,
@@ -380,6 +488,9 @@ func BuildDjotTokens(document []byte) tokenizer.TokenList[DjotToken] {
 				if lastBlockType != DocumentBlock && (tokenType == FootnoteDefBlock || tokenType == ReferenceDefBlock) {
 					continue
 				}
+				if depthLimitReached && tokenType != ParagraphBlock {
+					continue
+				}
 				block, next, ok := MatchBlockToken(reader, state, tokenType)
 				if !ok {
 					continue
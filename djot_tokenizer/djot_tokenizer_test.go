@@ -141,3 +141,157 @@ func TestUnmatchedEmphasis(t *testing.T) {
 		{Type: DocumentBlock ^ tokenizer.Open, Start: 37, End: 37, JumpToPair: -8},
 	}, tokens)
 }
+
+func TestInlineExtension(t *testing.T) {
+	mention := NewDjotToken("MentionInline")
+	wikiLink := NewDjotToken("WikiLinkInline")
+	options := Options{InlineExtensions: []InlineExtension{
+		{
+			Type:         mention,
+			StartSymbols: []byte("@"),
+			MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+				if next, ok := r.Token1(s, [...]byte{'@'}); ok {
+					return r.MaskRepeat(next, AttributeTokenMask, 1)
+				}
+				return 0, false
+			},
+		},
+		{
+			Type:         wikiLink,
+			StartSymbols: []byte("[]"),
+			MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+				return r.Token2(s, [...]byte{'[', '['})
+			},
+			MatchClose: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+				return r.Token2(s, [...]byte{']', ']'})
+			},
+			Verbatim: true,
+		},
+	}}
+	require.Equal(t, "MentionInline", mention.String())
+	require.Equal(t, "WikiLinkInlineClose", (wikiLink ^ tokenizer.Open).String())
+
+	tokens := options.BuildDjotTokens([]byte("hi @bob, see [[*Page*]]"))
+	require.Equal(t, tokenizer.TokenList[DjotToken]{
+		{Type: DocumentBlock, Start: 0, End: 0, JumpToPair: 9},
+		{Type: ParagraphBlock, Start: 0, End: 0, JumpToPair: 7},
+		{Type: None, Start: 0, End: 3},
+		{Type: mention, Start: 3, End: 7},
+		{Type: None, Start: 7, End: 13},
+		{Type: wikiLink, Start: 13, End: 15, JumpToPair: 2},
+		{Type: None, Start: 15, End: 21},
+		{Type: wikiLink ^ tokenizer.Open, Start: 21, End: 23, JumpToPair: -2},
+		{Type: ParagraphBlock ^ tokenizer.Open, Start: 23, End: 23, JumpToPair: -7},
+		{Type: DocumentBlock ^ tokenizer.Open, Start: 23, End: 23, JumpToPair: -9},
+	}, tokens)
+	require.Equal(t, BuildDjotTokens([]byte("hi @bob")), Options{}.BuildDjotTokens([]byte("hi @bob")))
+}
+
+func TestBlockExtension(t *testing.T) {
+	fence := NewDjotToken("FenceBlock")
+	matchFence := func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+		if next, ok := r.Token(s, "---"); ok {
+			return r.EmptyOrWhiteSpace(next)
+		}
+		return 0, false
+	}
+	options := Options{BlockExtensions: []BlockExtension{{
+		Type: fence,
+		MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[DjotToken], tokenizer.ReaderState, bool) {
+			// fence is allowed only at the very beginning of the document
+			if s != 0 {
+				return tokenizer.Token[DjotToken]{}, 0, false
+			}
+			next, ok := matchFence(r, s)
+			return tokenizer.Token[DjotToken]{Start: s, End: next}, next, ok
+		},
+		MatchClose: func(r tokenizer.TextReader, s tokenizer.ReaderState, _ tokenizer.Token[DjotToken]) (tokenizer.ReaderState, bool) {
+			return matchFence(r, s)
+		},
+	}}}
+	tokens := options.BuildDjotTokens([]byte("---\n# a\n---\n---\n"))
+	require.Equal(t, tokenizer.TokenList[DjotToken]{
+		{Type: DocumentBlock, Start: 0, End: 0, JumpToPair: 5},
+		{Type: fence, Start: 0, End: 4, JumpToPair: 2},
+		{Type: None, Start: 4, End: 8},
+		{Type: fence ^ tokenizer.Open, Start: 8, End: 12, JumpToPair: -2},
+		{Type: ThematicBreakToken, Start: 12, End: 16},
+		{Type: DocumentBlock ^ tokenizer.Open, Start: 16, End: 16, JumpToPair: -5},
+	}, tokens)
+	// block extensions take precedence over the front matter
+	options.FrontMatter = true
+	require.Equal(t, tokens, options.BuildDjotTokens([]byte("---\n# a\n---\n---\n")))
+}
+
+func TestBlockExtensionContinue(t *testing.T) {
+	aside := NewDjotToken("AsideBlock")
+	matchBar := func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool) {
+		if next, ok := r.Token(s, "|"); ok {
+			return r.MaskRepeat(next, tokenizer.SpaceByteMask, 0)
+		}
+		return 0, false
+	}
+	options := Options{BlockExtensions: []BlockExtension{{
+		Type: aside,
+		MatchOpen: func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[DjotToken], tokenizer.ReaderState, bool) {
+			next, ok := matchBar(r, s)
+			return tokenizer.Token[DjotToken]{Start: s, End: next}, next, ok
+		},
+		MatchContinue: func(r tokenizer.TextReader, s tokenizer.ReaderState, _ tokenizer.Token[DjotToken]) (tokenizer.ReaderState, bool) {
+			return matchBar(r, s)
+		},
+		Container: true,
+	}}}
+	require.Nil(t, options.Validate())
+	tokens := options.BuildDjotTokens([]byte("| a\n|\n| - b\ntext\n"))
+	require.Equal(t, tokenizer.TokenList[DjotToken]{
+		{Type: DocumentBlock, Start: 0, End: 0, JumpToPair: 17},
+		{Type: aside, Start: 0, End: 2, JumpToPair: 11},
+		{Type: ParagraphBlock, Start: 2, End: 2, JumpToPair: 3},
+		{Type: None, Start: 2, End: 3},
+		{Type: SmartSymbolInline, Start: 3, End: 4},
+		{Type: ParagraphBlock ^ tokenizer.Open, Start: 5, End: 5, JumpToPair: -3},
+		{Type: ListItemBlock, Start: 8, End: 10, JumpToPair: 5},
+		{Type: ParagraphBlock, Start: 10, End: 10, JumpToPair: 3},
+		{Type: None, Start: 10, End: 11},
+		{Type: SmartSymbolInline, Start: 11, End: 12},
+		{Type: ParagraphBlock ^ tokenizer.Open, Start: 12, End: 12, JumpToPair: -3},
+		{Type: ListItemBlock ^ tokenizer.Open, Start: 12, End: 12, JumpToPair: -5},
+		{Type: aside ^ tokenizer.Open, Start: 12, End: 12, JumpToPair: -11},
+		{Type: ParagraphBlock, Start: 12, End: 12, JumpToPair: 3},
+		{Type: None, Start: 12, End: 16},
+		{Type: SmartSymbolInline, Start: 16, End: 17},
+		{Type: ParagraphBlock ^ tokenizer.Open, Start: 17, End: 17, JumpToPair: -3},
+		{Type: DocumentBlock ^ tokenizer.Open, Start: 17, End: 17, JumpToPair: -17},
+	}, tokens)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	block := NewDjotToken("ValidatedBlock")
+	matchOpen := func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[DjotToken], tokenizer.ReaderState, bool) {
+		return tokenizer.Token[DjotToken]{}, s, false
+	}
+	require.Nil(t, Options{}.Validate())
+	require.EqualError(t, Options{BlockExtensions: []BlockExtension{{Type: block, MatchOpen: matchOpen}}}.Validate(),
+		"block extension ValidatedBlock: either MatchClose or MatchContinue must be set")
+	require.EqualError(t, Options{BlockExtensions: []BlockExtension{{Type: ParagraphBlock, MatchOpen: matchOpen}}}.Validate(),
+		"block extension #0: type ParagraphBlock is not allocated with NewDjotToken")
+	require.EqualError(t, Options{InlineExtensions: []InlineExtension{{Type: block, StartSymbols: []byte("@")}}}.Validate(),
+		"inline extension ValidatedBlock: MatchOpen must be set")
+}
+
+func TestInterrupt(t *testing.T) {
+	document := []byte("# a\n\n_b *c* d_\n\ne\n")
+	calls := 0
+	tokens := Options{Interrupt: func() bool { calls++; return calls > 3 }}.BuildDjotTokens(document)
+	require.Less(t, len(tokens), len(BuildDjotTokens(document)))
+	for i, token := range tokens {
+		pair := tokens[i+token.JumpToPair]
+		require.Equal(t, token.Type&^tokenizer.Open, pair.Type&^tokenizer.Open, "token %v must be paired", i)
+		require.Equal(t, i, i+token.JumpToPair+pair.JumpToPair)
+	}
+	require.Equal(t, tokenizer.TokenList[DjotToken]{
+		{Type: DocumentBlock, JumpToPair: 1},
+		{Type: DocumentBlock ^ tokenizer.Open, Start: len(document), End: len(document), JumpToPair: -1},
+	}, Options{Interrupt: func() bool { return true }}.BuildDjotTokens(document))
+}
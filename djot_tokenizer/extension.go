@@ -0,0 +1,211 @@
+package djot_tokenizer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/sivukhin/godjot/v2/tokenizer"
+)
+
+// Options configures BuildDjotTokens / BuildInlineDjotTokens
+// Zero value corresponds to the plain djot syntax without any extensions
+type Options struct {
+	InlineExtensions []InlineExtension
+	BlockExtensions  []BlockExtension
+	// MaxNestingDepth limits nesting of the block elements (quotes, list items, divs, ...) and MaxInlineDelimiters limits
+	// number of simultaneously open inline elements: markup beyond the limit is treated as plain text (zero means no limit)
+	MaxNestingDepth     int
+	MaxInlineDelimiters int
+	// FrontMatter enables metadata block fenced with --- lines at the very start of the document (see MatchMetadataBlock);
+	// otherwise --- lines are regular thematic breaks
+	FrontMatter bool
+	// Interrupt (optional) is called regularly during tokenization: once it returns true, the rest of the document is skipped
+	// and tokens built so far are returned (still properly paired), so caller can bound the time spent on the untrusted input
+	Interrupt func() bool
+}
+
+// InlineExtension describes custom inline syntax which tokenizer recognizes in addition to the built-in djot elements:
+//   - Type must be allocated with NewDjotToken
+//   - StartSymbols must contain all bytes which can start open or close token of the extension
+//   - MatchOpen / MatchClose follow MatchInlineToken contract: they return reader state right after the matched token
+//   - extension without MatchClose produces single unpaired token (similar to SmartSymbolInline)
+//   - Verbatim extension content is not tokenized until the close token is found (similar to VerbatimInline)
+//
+// Extensions are matched before built-in paired inline elements, so they can take precedence over them (e.g. [[wiki link]])
+type InlineExtension struct {
+	Type         DjotToken
+	StartSymbols []byte
+	MatchOpen    func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool)
+	MatchClose   func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.ReaderState, bool)
+	Verbatim     bool
+}
+
+// BlockExtension describes custom block syntax which tokenizer recognizes in addition to the built-in djot blocks:
+//   - Type must be allocated with NewDjotToken
+//   - MatchOpen is called at the start of the line (after indentation) and returns open token (which can carry attributes)
+//   - MatchClose is called at the start of every line inside the block and returns state right after the close token (fenced blocks)
+//   - MatchContinue is called at the start of every line inside the block and returns state right after the line prefix of the block
+//     (e.g. indentation or `|` marker); first line which doesn't match closes the block (similar to QuoteBlock but without lazy continuation)
+//   - Container block content is parsed as regular djot blocks (similar to DivBlock), otherwise content is kept verbatim (similar to CodeBlock)
+//
+// At least one of MatchClose / MatchContinue must be set (MatchContinue is checked first if both are set).
+// Extensions are matched before built-in blocks and take precedence over thematic breaks, so they can use fences like `+++` or `---`
+type BlockExtension struct {
+	Type          DjotToken
+	MatchOpen     func(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[DjotToken], tokenizer.ReaderState, bool)
+	MatchClose    func(r tokenizer.TextReader, s tokenizer.ReaderState, open tokenizer.Token[DjotToken]) (tokenizer.ReaderState, bool)
+	MatchContinue func(r tokenizer.TextReader, s tokenizer.ReaderState, open tokenizer.Token[DjotToken]) (tokenizer.ReaderState, bool)
+	Container     bool
+}
+
+func (o Options) interrupted() bool { return o.Interrupt != nil && o.Interrupt() }
+
+// Validate returns error if some extension violates InlineExtension / BlockExtension contract
+// (BuildDjotTokens doesn't validate options and can panic for the invalid extensions)
+func (o Options) Validate() error {
+	for i, extension := range o.InlineExtensions {
+		if _, ok := extensionTokenName(extension.Type); !ok {
+			return fmt.Errorf("inline extension #%v: type %v is not allocated with NewDjotToken", i, extension.Type)
+		}
+		if extension.MatchOpen == nil {
+			return fmt.Errorf("inline extension %v: MatchOpen must be set", extension.Type)
+		}
+		if len(extension.StartSymbols) == 0 {
+			return fmt.Errorf("inline extension %v: StartSymbols must be set", extension.Type)
+		}
+		if extension.Verbatim && extension.MatchClose == nil {
+			return fmt.Errorf("inline extension %v: verbatim extension must have MatchClose", extension.Type)
+		}
+	}
+	for i, extension := range o.BlockExtensions {
+		if _, ok := extensionTokenName(extension.Type); !ok {
+			return fmt.Errorf("block extension #%v: type %v is not allocated with NewDjotToken", i, extension.Type)
+		}
+		if extension.MatchOpen == nil {
+			return fmt.Errorf("block extension %v: MatchOpen must be set", extension.Type)
+		}
+		if extension.MatchClose == nil && extension.MatchContinue == nil {
+			return fmt.Errorf("block extension %v: either MatchClose or MatchContinue must be set", extension.Type)
+		}
+	}
+	return nil
+}
+
+var (
+	tokenNamesMutex = sync.RWMutex{}
+	tokenNames      = make(map[DjotToken]string)
+	nextToken       = DjotToken(MetadataBlock + 2)
+)
+
+// NewDjotToken allocates new token type for the custom syntax (paired close type is the t ^ tokenizer.Open)
+// name is used only for DjotToken.String() and must be unique across all extensions
+func NewDjotToken(name string) DjotToken {
+	tokenNamesMutex.Lock()
+	defer tokenNamesMutex.Unlock()
+
+	token := nextToken
+	nextToken += 2
+	tokenNames[token] = name
+	return token
+}
+
+func extensionTokenName(t DjotToken) (string, bool) {
+	tokenNamesMutex.RLock()
+	defer tokenNamesMutex.RUnlock()
+
+	name, ok := tokenNames[t]
+	return name, ok
+}
+
+type inlineExtensionMatcher struct {
+	InlineExtension
+	startSymbols tokenizer.ByteMask
+}
+
+func (o Options) inlineMatchers() (tokenizer.ByteMask, []inlineExtensionMatcher) {
+	if len(o.InlineExtensions) == 0 {
+		return InlineTokenStartSymbol, nil
+	}
+	startSymbols := InlineTokenStartSymbol
+	matchers := make([]inlineExtensionMatcher, 0, len(o.InlineExtensions))
+	for _, extension := range o.InlineExtensions {
+		mask := tokenizer.NewByteMask(extension.StartSymbols)
+		startSymbols = startSymbols.Or(mask)
+		matchers = append(matchers, inlineExtensionMatcher{InlineExtension: extension, startSymbols: mask})
+	}
+	return startSymbols, matchers
+}
+
+func findInlineMatcher(matchers []inlineExtensionMatcher, tokenType DjotToken) *inlineExtensionMatcher {
+	for i := range matchers {
+		if matchers[i].Type == tokenType {
+			return &matchers[i]
+		}
+	}
+	return nil
+}
+
+func (o Options) findBlockExtension(tokenType DjotToken) *BlockExtension {
+	for i := range o.BlockExtensions {
+		if o.BlockExtensions[i].Type == tokenType {
+			return &o.BlockExtensions[i]
+		}
+	}
+	return nil
+}
+
+// isVerbatimBlock returns true for blocks which content must be kept as is (CodeBlock or non-container extension)
+func (o Options) isVerbatimBlock(tokenType DjotToken) bool {
+	if tokenType == CodeBlock {
+		return true
+	}
+	extension := o.findBlockExtension(tokenType)
+	return extension != nil && !extension.Container
+}
+
+// isFencedContainer returns true for blocks which contain nested blocks and can be closed explicitly (DivBlock or container extension)
+func (o Options) isFencedContainer(tokenType DjotToken) bool {
+	if tokenType == DivBlock {
+		return true
+	}
+	extension := o.findBlockExtension(tokenType)
+	return extension != nil && extension.Container
+}
+
+func (o Options) matchBlockExtension(r tokenizer.TextReader, s tokenizer.ReaderState) (tokenizer.Token[DjotToken], tokenizer.ReaderState, bool) {
+	if len(o.BlockExtensions) == 0 {
+		return tokenizer.Token[DjotToken]{}, s, false
+	}
+	s, ok := r.MaskRepeat(s, tokenizer.SpaceByteMask, 0)
+	tokenizer.Assertf(ok, "MaskRepeat must match because minCount is zero")
+	for _, extension := range o.BlockExtensions {
+		if token, next, ok := extension.MatchOpen(r, s); ok {
+			token.Type = extension.Type
+			return token, next, true
+		}
+	}
+	return tokenizer.Token[DjotToken]{}, s, false
+}
+
+func (o Options) matchBlockExtensionClose(
+	r tokenizer.TextReader,
+	s tokenizer.ReaderState,
+	open tokenizer.Token[DjotToken],
+) (tokenizer.Token[DjotToken], bool) {
+	s, ok := r.MaskRepeat(s, tokenizer.SpaceByteMask, 0)
+	tokenizer.Assertf(ok, "MaskRepeat must match because minCount is zero")
+	extension := o.findBlockExtension(open.Type)
+	if extension.MatchClose == nil {
+		return tokenizer.Token[DjotToken]{}, false
+	}
+	next, ok := extension.MatchClose(r, s, open)
+	if !ok {
+		return tokenizer.Token[DjotToken]{}, false
+	}
+	return tokenizer.Token[DjotToken]{Type: open.Type ^ tokenizer.Open, Start: s, End: next}, true
+}
+
+func (o Options) hasBlockExtensionAt(r tokenizer.TextReader, s tokenizer.ReaderState) bool {
+	_, _, ok := o.matchBlockExtension(r, s)
+	return ok
+}
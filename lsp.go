@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/sivukhin/godjot/v2/djot_lsp"
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+func runLsp(args []string) {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	var refs stringsFlag
+	frontMatter := flags.Bool("front-matter", false, "parse metadata block (front matter) at the start of the documents")
+	flags.Var(&refs, "refs", "path to the djot file with shared reference definitions and footnotes (can be specified multiple times)")
+	_ = flags.Parse(args)
+	references, _, err := loadReferences(refs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := djot_lsp.NewServer(djot_parser.NewParser(djot_parser.Options{References: references, FrontMatter: *frontMatter}))
+	if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("language server failed: %v", err)
+	}
+}
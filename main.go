@@ -1,19 +1,79 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/sivukhin/godjot/v2/djot_html"
 	"github.com/sivukhin/godjot/v2/djot_parser"
 )
 
+// stringsFlag accumulates values of the flag which can be specified multiple times
+type stringsFlag []string
+
+func (f *stringsFlag) String() string     { return strings.Join(*f, ",") }
+func (f *stringsFlag) Set(v string) error { *f = append(*f, v); return nil }
+
+// loadReferences parses shared references from the djot files and returns the latest modification time of these files
+func loadReferences(paths []string) (djot_parser.References, time.Time, error) {
+	var (
+		references djot_parser.References
+		modified   time.Time
+	)
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return references, modified, fmt.Errorf("failed to read references file %v: %w", path, err)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return references, modified, fmt.Errorf("failed to read references file %v: %w", path, err)
+		}
+		references.Merge(djot_parser.ParseReferences(content))
+		if stat.ModTime().After(modified) {
+			modified = stat.ModTime()
+		}
+	}
+	return references, modified, nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		runBuild(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		runLsp(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "server" {
+		runServer(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tasks" {
+		runTasks(os.Args[2:])
+		return
+	}
 	from := flag.String("from", "", "path to the input djot file (empty or '-' for stdin)")
 	to := flag.String("to", "", "path to the output html file (empty or '-' for stdout)")
 	overwrite := flag.Bool("overwrite", false, "overwrite output html file")
+	metadata := flag.Bool("metadata", false, "output document metadata (front matter) as JSON instead of html (implies -front-matter)")
+	frontMatter := flag.Bool("front-matter", false, "parse metadata block (front matter) at the start of the document")
+	title := flag.String("title", "", "title of the standalone document (document metadata or first heading is used if empty)")
+	standalone := registerStandaloneFlags(flag.CommandLine)
+	var refs stringsFlag
+	flag.Var(&refs, "refs", "path to the djot file with shared reference definitions and footnotes (can be specified multiple times)")
 	flag.Parse()
 
 	var inReader io.Reader
@@ -40,17 +100,41 @@ func main() {
 		}
 		outWriter = f
 	}
+	standaloneOptions, _, err := standalone.options()
+	if err != nil {
+		log.Fatal(err)
+	}
+	standaloneOptions.Title = *title
+	references, _, err := loadReferences(refs)
+	if err != nil {
+		log.Fatal(err)
+	}
 	input, err := io.ReadAll(inReader)
 	if err != nil {
 		log.Fatalf("failed to read input file %v: %v", *from, err)
 	}
-	ast := djot_parser.BuildDjotAst(input)
-	html := []byte(djot_html.New().ConvertDjot(&djot_html.HtmlWriter{}, ast...).String())
-	for len(html) > 0 {
-		n, err := outWriter.Write(html)
+	document := djot_parser.Options{References: references, FrontMatter: *frontMatter || *metadata}.BuildDjotDocument(input)
+	var output []byte
+	if *metadata {
+		output, err = json.MarshalIndent(document.Metadata, "", "  ")
+		if err != nil {
+			log.Fatalf("failed to serialize metadata: %v", err)
+		}
+	} else if standalone.standalone {
+		var buffer bytes.Buffer
+		err = djot_html.RenderStandalone(&buffer, djot_html.New(), document, standaloneOptions)
+		if err != nil {
+			log.Fatalf("failed to render standalone document: %v", err)
+		}
+		output = buffer.Bytes()
+	} else {
+		output = []byte(djot_html.New().ConvertDjotDocument(&djot_html.HtmlWriter{}, document).String())
+	}
+	for len(output) > 0 {
+		n, err := outWriter.Write(output)
 		if err != nil {
 			log.Fatalf("failed to write output file %v: %v", *to, err)
 		}
-		html = html[n:]
+		output = output[n:]
 	}
 }
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+// serveEventsPath is the Server-Sent Events endpoint which notifies preview pages about changed files
+const serveEventsPath = "/.godjot/events"
+
+// reloadScript reloads preview page on every change event and restores scroll position after the reload
+const reloadScript = `<script>
+(function() {
+  var key = "godjot-scroll:" + location.pathname;
+  var scroll = sessionStorage.getItem(key);
+  if (scroll !== null) {
+    sessionStorage.removeItem(key);
+    window.addEventListener("load", function() { window.scrollTo(0, parseInt(scroll, 10)); });
+  }
+  new EventSource("` + serveEventsPath + `").addEventListener("reload", function() {
+    sessionStorage.setItem(key, String(window.scrollY));
+    location.reload();
+  });
+})();
+</script>
+`
+
+var listingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{ .Path }}</title>
+</head>
+<body>
+<h1>{{ .Path }}</h1>
+<ul>
+{{- range .Entries }}
+<li><a href="{{ .Href }}">{{ .Name }}</a></li>
+{{- end }}
+</ul>
+</body>
+</html>
+`))
+
+type listingEntry struct {
+	Name string
+	Href string
+}
+
+// previewServer renders djot files of the directory on request (godjot serve)
+type previewServer struct {
+	dir         string
+	frontMatter bool
+
+	mu      sync.RWMutex
+	options buildOptions
+
+	clientsMu sync.Mutex
+	clients   map[chan string]struct{}
+}
+
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	dir := flags.String("dir", ".", "path to the directory with djot files")
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	interval := flags.Duration("interval", time.Second, "polling interval for the changes in the directory")
+	frontMatter := flags.Bool("front-matter", false, "parse metadata block (front matter) at the start of the documents")
+	standalone := registerStandaloneFlags(flags)
+	var refs stringsFlag
+	flags.Var(&refs, "refs", "path to the djot file with shared reference definitions and footnotes (can be specified multiple times)")
+	_ = flags.Parse(args)
+
+	server := &previewServer{dir: *dir, frontMatter: *frontMatter, clients: make(map[chan string]struct{})}
+	if err := server.reload(standalone, refs); err != nil {
+		log.Fatal(err)
+	}
+	watched := append([]string{standalone.templatePath}, refs...)
+	go server.watch(*interval, watched, func() {
+		if err := server.reload(standalone, refs); err != nil {
+			log.Printf("failed to reload configuration: %v", err)
+		}
+	})
+	log.Printf("serving %v on http://%v", *dir, *addr)
+	if err := http.ListenAndServe(*addr, server); err != nil {
+		log.Fatalf("preview server failed: %v", err)
+	}
+}
+
+// reload reads template and shared references files again
+func (s *previewServer) reload(standalone *standaloneFlags, refs []string) error {
+	options := buildOptions{Parser: djot_parser.Options{FrontMatter: s.frontMatter}}
+	if standalone.standalone {
+		standaloneOptions, _, err := standalone.options()
+		if err != nil {
+			return err
+		}
+		options.Standalone = &standaloneOptions
+	}
+	references, _, err := loadReferences(refs)
+	if err != nil {
+		return err
+	}
+	options.Parser.References = references
+	s.mu.Lock()
+	s.options = options
+	s.mu.Unlock()
+	return nil
+}
+
+// watch polls modification times of the directory files (and additional watched files) and broadcasts reload event on every change;
+// onExternalChange is called before the broadcast if one of the additional files was changed
+func (s *previewServer) watch(interval time.Duration, watched []string, onExternalChange func()) {
+	files, external := s.snapshot(), snapshotFiles(watched)
+	for {
+		time.Sleep(interval)
+		nextFiles, nextExternal := s.snapshot(), snapshotFiles(watched)
+		changed := changedFiles(files, nextFiles)
+		if externalChanged := changedFiles(external, nextExternal); len(externalChanged) > 0 {
+			onExternalChange()
+			changed = append(changed, externalChanged...)
+		}
+		files, external = nextFiles, nextExternal
+		for _, file := range changed {
+			log.Printf("changed %v", file)
+		}
+		if len(changed) > 0 {
+			s.broadcast(changed[0])
+		}
+	}
+}
+
+// fileState is the modification time and size of the file (zero value for the missing file)
+type fileState struct {
+	modified time.Time
+	size     int64
+}
+
+func (s *previewServer) snapshot() map[string]fileState {
+	files := make(map[string]fileState)
+	_ = filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil {
+			files[path] = fileState{modified: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files
+}
+
+func snapshotFiles(paths []string) map[string]fileState {
+	files := make(map[string]fileState)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			files[path] = fileState{modified: info.ModTime(), size: info.Size()}
+		} else {
+			files[path] = fileState{}
+		}
+	}
+	return files
+}
+
+// changedFiles returns sorted list of the files which were added, removed or modified
+func changedFiles(previous, current map[string]fileState) []string {
+	var changed []string
+	for path, state := range current {
+		if previousState, ok := previous[path]; !ok || !previousState.modified.Equal(state.modified) || previousState.size != state.size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func (s *previewServer) broadcast(path string) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for client := range s.clients {
+		select {
+		case client <- path:
+		default:
+			// client already has pending reload event
+		}
+	}
+}
+
+func (s *previewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Path == serveEventsPath {
+		s.serveEvents(w, r)
+		return
+	}
+	// path.Clean of the rooted path never escapes the root
+	urlPath := path.Clean("/" + r.URL.Path)
+	target := filepath.Join(s.dir, filepath.FromSlash(urlPath))
+	info, err := os.Stat(target)
+	switch {
+	case err == nil && info.IsDir():
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+		if index := filepath.Join(target, "index"+djotExtension); isFile(index) {
+			s.serveDjot(w, index)
+		} else {
+			s.serveListing(w, urlPath, target)
+		}
+	case err == nil && strings.HasSuffix(target, djotExtension):
+		s.serveDjot(w, target)
+	case err == nil:
+		http.ServeFile(w, r, target)
+	case strings.HasSuffix(target, ".html") && isFile(strings.TrimSuffix(target, ".html")+djotExtension):
+		// links between djot files are rewritten to html files, so html file is rendered from the djot source
+		s.serveDjot(w, strings.TrimSuffix(target, ".html")+djotExtension)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+func (s *previewServer) serveDjot(w http.ResponseWriter, source string) {
+	input, err := os.ReadFile(source)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.mu.RLock()
+	options := s.options
+	s.mu.RUnlock()
+	output, err := renderDjot(options, input)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to render %v: %v", source, err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(injectReloadScript(output))
+}
+
+// injectReloadScript inserts reload script before closing body tag (or at the end of the html fragment)
+func injectReloadScript(page []byte) []byte {
+	at := bytes.LastIndex(page, []byte("</body>"))
+	if at == -1 {
+		at = len(page)
+	}
+	result := make([]byte, 0, len(page)+len(reloadScript))
+	result = append(result, page[:at]...)
+	result = append(result, reloadScript...)
+	return append(result, page[at:]...)
+}
+
+func (s *previewServer) serveListing(w http.ResponseWriter, urlPath, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	listing := make([]listingEntry, 0, len(entries))
+	if urlPath != "/" {
+		listing = append(listing, listingEntry{Name: "../", Href: "../"})
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case entry.IsDir():
+			listing = append(listing, listingEntry{Name: name + "/", Href: name + "/"})
+		case strings.HasSuffix(name, djotExtension):
+			listing = append(listing, listingEntry{Name: name, Href: strings.TrimSuffix(name, djotExtension) + ".html"})
+		default:
+			listing = append(listing, listingEntry{Name: name, Href: name})
+		}
+	}
+	var output bytes.Buffer
+	if err := listingTemplate.Execute(&output, map[string]any{"Path": urlPath, "Entries": listing}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(injectReloadScript(output.Bytes()))
+}
+
+// serveEvents streams reload events until client disconnects
+func (s *previewServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	client := make(chan string, 1)
+	s.clientsMu.Lock()
+	s.clients[client] = struct{}{}
+	s.clientsMu.Unlock()
+	defer func() {
+		s.clientsMu.Lock()
+		delete(s.clients, client)
+		s.clientsMu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case changed := <-client:
+			_, _ = fmt.Fprintf(w, "event: reload\ndata: %v\n\n", filepath.ToSlash(changed))
+			flusher.Flush()
+		}
+	}
+}
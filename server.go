@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+	"github.com/sivukhin/godjot/v2/djot_server"
+)
+
+func runServer(args []string) {
+	flags := flag.NewFlagSet("server", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	safe := flags.Bool("safe", true, "render html in the safe mode (raw html is skipped, unsafe urls and attributes are removed)")
+	maxBody := flags.Int64("max-body", 1<<20, "maximum size of the request body in bytes")
+	maxConcurrent := flags.Int("max-concurrent", runtime.NumCPU(), "maximum number of simultaneously rendered requests")
+	timeout := flags.Duration("timeout", 10*time.Second, "request processing timeout")
+	maxNodes := flags.Int("max-nodes", djot_parser.DefaultLimits.MaxNodes, "maximum number of AST nodes in the document (0 for no limit)")
+	maxDepth := flags.Int("max-depth", djot_parser.DefaultLimits.MaxNestingDepth, "maximum nesting depth of blocks (0 for no limit)")
+	frontMatter := flags.Bool("front-matter", false, "parse metadata block (front matter) at the start of the documents")
+	var refs stringsFlag
+	flags.Var(&refs, "refs", "path to the djot file with shared reference definitions and footnotes (can be specified multiple times)")
+	_ = flags.Parse(args)
+	references, _, err := loadReferences(refs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	limits := djot_parser.DefaultLimits
+	limits.MaxInputSize, limits.MaxNodes, limits.MaxNestingDepth = int(*maxBody), *maxNodes, *maxDepth
+	handler := djot_server.NewServer(djot_server.Config{
+		Parser:        djot_parser.Options{References: references, Limits: limits, FrontMatter: *frontMatter},
+		Safe:          *safe,
+		MaxBodySize:   *maxBody,
+		MaxConcurrent: *maxConcurrent,
+		Timeout:       *timeout,
+	})
+	server := &http.Server{Addr: *addr, Handler: handler, ReadHeaderTimeout: *timeout}
+	log.Printf("listening on http://%v", *addr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("rendering server failed: %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sivukhin/godjot/v2/djot_parser"
+)
+
+// fileTask is the task found in the djot file (godjot tasks)
+type fileTask struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+	Depth   int    `json:"depth"`
+	Heading string `json:"heading,omitempty"`
+}
+
+func runTasks(args []string) {
+	flags := flag.NewFlagSet("tasks", flag.ExitOnError)
+	dir := flags.String("dir", ".", "path to the directory with djot files")
+	all := flags.Bool("all", false, "include completed tasks")
+	asJson := flags.Bool("json", false, "output tasks as JSON array")
+	_ = flags.Parse(args)
+	tasks, err := collectTasks(*dir, *all)
+	if err != nil {
+		log.Fatalf("failed to collect tasks: %v", err)
+	}
+	if *asJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(tasks); err != nil {
+			log.Fatalf("failed to serialize tasks: %v", err)
+		}
+		return
+	}
+	writeTasks(os.Stdout, tasks)
+}
+
+// collectTasks returns tasks from all djot files of the directory (only open tasks unless all is set)
+func collectTasks(dir string, all bool) ([]fileTask, error) {
+	tasks := make([]fileTask, 0)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(path, djotExtension) {
+			return nil
+		}
+		document, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := djot_parser.NewSourceLines(document)
+		for _, task := range djot_parser.Tasks(document) {
+			if task.Checked && !all {
+				continue
+			}
+			tasks = append(tasks, fileTask{
+				Path:    path,
+				Line:    lines.Position(task.Range.Start).Line + 1,
+				Text:    task.Text,
+				Checked: task.Checked,
+				Depth:   task.Depth,
+				Heading: task.Heading,
+			})
+		}
+		return nil
+	})
+	return tasks, err
+}
+
+// writeTasks writes tasks grouped by file and heading
+func writeTasks(w io.Writer, tasks []fileTask) {
+	path, heading := "", ""
+	for i, task := range tasks {
+		if task.Path != path {
+			if i > 0 {
+				_, _ = fmt.Fprintln(w)
+			}
+			path, heading = task.Path, ""
+			_, _ = fmt.Fprintln(w, path)
+		}
+		if task.Heading != heading {
+			heading = task.Heading
+			_, _ = fmt.Fprintf(w, "  # %v\n", heading)
+		}
+		checkbox := "[ ]"
+		if task.Checked {
+			checkbox = "[x]"
+		}
+		_, _ = fmt.Fprintf(w, "  %v%v %v (line %v)\n", strings.Repeat("  ", task.Depth), checkbox, task.Text, task.Line)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectTasks(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"todo.djot":       "- [ ] first\n\n# Home\n\n- [x] done\n- [ ] second\n\n  - [ ] nested\n",
+		"docs/notes.djot": "# Notes\n\n- [ ] read\n",
+		"docs/notes.txt":  "- [ ] not djot\n",
+	})
+	todo, notes := filepath.Join(dir, "todo.djot"), filepath.Join(dir, "docs", "notes.djot")
+	for _, tt := range []struct {
+		name  string
+		all   bool
+		tasks []fileTask
+	}{
+		{
+			name: "open tasks",
+			tasks: []fileTask{
+				{Path: notes, Line: 3, Text: "read", Heading: "Notes"},
+				{Path: todo, Line: 1, Text: "first"},
+				{Path: todo, Line: 6, Text: "second", Heading: "Home"},
+				{Path: todo, Line: 8, Text: "nested", Depth: 1, Heading: "Home"},
+			},
+		},
+		{
+			name: "all tasks",
+			all:  true,
+			tasks: []fileTask{
+				{Path: notes, Line: 3, Text: "read", Heading: "Notes"},
+				{Path: todo, Line: 1, Text: "first"},
+				{Path: todo, Line: 5, Text: "done", Checked: true, Heading: "Home"},
+				{Path: todo, Line: 6, Text: "second", Heading: "Home"},
+				{Path: todo, Line: 8, Text: "nested", Depth: 1, Heading: "Home"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := collectTasks(dir, tt.all)
+			require.Nil(t, err)
+			require.Equal(t, tt.tasks, tasks)
+		})
+	}
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := collectTasks(filepath.Join(dir, "missing"), false)
+		require.NotNil(t, err)
+	})
+}
+
+func TestWriteTasks(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		tasks  []fileTask
+		output string
+	}{
+		{name: "empty", tasks: []fileTask{}, output: ""},
+		{
+			name: "grouped by file and heading",
+			tasks: []fileTask{
+				{Path: "a.djot", Line: 1, Text: "first"},
+				{Path: "a.djot", Line: 5, Text: "done", Checked: true, Heading: "Home"},
+				{Path: "a.djot", Line: 8, Text: "nested", Depth: 1, Heading: "Home"},
+				{Path: "b.djot", Line: 3, Text: "read", Heading: "Home"},
+			},
+			output: `a.djot
+  [ ] first (line 1)
+  # Home
+  [x] done (line 5)
+    [ ] nested (line 8)
+
+b.djot
+  # Home
+  [ ] read (line 3)
+`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			writeTasks(&output, tt.tasks)
+			require.Equal(t, tt.output, output.String())
+		})
+	}
+}
@@ -2,6 +2,7 @@ package tokenizer
 
 import (
 	"fmt"
+	"iter"
 	"strings"
 )
 
@@ -45,3 +46,18 @@ func (l TokenList[T]) GoString() string {
 	}
 	return strings.Join(tokens, ",")
 }
+
+// Pairs iterates over paired tokens of the list in the order of their open tokens: every open token is yielded together with
+// its closing token (found with JumpToPair)
+func (l TokenList[T]) Pairs() iter.Seq2[Token[T], Token[T]] {
+	return func(yield func(Token[T], Token[T]) bool) {
+		for i, token := range l {
+			if token.JumpToPair <= 0 || i+token.JumpToPair >= len(l) {
+				continue
+			}
+			if !yield(token, l[i+token.JumpToPair]) {
+				return
+			}
+		}
+	}
+}
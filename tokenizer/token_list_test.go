@@ -16,3 +16,22 @@ func TestTokenList(t *testing.T) {
 		{Type: 2, Start: 10, End: 11},
 	}, l)
 }
+
+func TestTokenListPairs(t *testing.T) {
+	l := TokenList[int]{
+		{Type: 2, JumpToPair: 3},
+		{Type: 4, JumpToPair: 1},
+		{Type: 5, JumpToPair: -1},
+		{Type: 3, JumpToPair: -3},
+		{Type: 6},
+	}
+	var pairs [][2]int
+	for open, close := range l.Pairs() {
+		pairs = append(pairs, [2]int{open.Type, close.Type})
+	}
+	require.Equal(t, [][2]int{{2, 3}, {4, 5}}, pairs)
+	for open := range l.Pairs() {
+		require.Equal(t, 2, open.Type)
+		break
+	}
+}
@@ -82,9 +82,23 @@ func (s *TokenStack[T]) PopForgetUntil(tokenType T) bool {
 		return false
 	}
 	lastLevel := levels[len(levels)-1]
-	for len(s.Levels) > lastLevel+1 {
-		s.PopForget()
+	// tokens of all forgotten levels are moved at once: popping levels one by one moves the same tokens
+	// again and again which is quadratic for the long runs of unmatched open tokens
+	activeLevel := &s.Levels[lastLevel]
+	for i := lastLevel + 1; i < len(s.Levels); i++ {
+		popLevel := s.Levels[i]
+		if typeLevels := s.TypeLevels[popLevel.FirstOrDefault().Type]; len(typeLevels) > 0 {
+			s.TypeLevels[popLevel.FirstOrDefault().Type] = typeLevels[0 : len(typeLevels)-1]
+		}
+		for _, token := range popLevel[1:] {
+			if token.IsDefault() {
+				continue
+			}
+			activeLevel.Push(token)
+		}
+		s.Levels[i] = nil
 	}
+	s.Levels = s.Levels[0 : lastLevel+1]
 	return true
 }
 